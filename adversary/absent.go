@@ -21,13 +21,13 @@ func (a *Absent) ID() f3.ActorID {
 	return a.id
 }
 
-func (a *Absent) ReceiveCanonicalChain(_ f3.ECChain, _ f3.PowerTable, _ []byte) {
+func (a *Absent) ReceiveCanonicalChain(_ f3.ECChain) {
 }
 
 func (a *Absent) ReceiveMessage(_ *f3.GMessage) {
 }
 
-func (a *Absent) ReceiveAlarm(_ string) {
+func (a *Absent) ReceiveAlarm(_ f3.Alarm) {
 }
 
 func (a *Absent) AllowMessage(_ f3.ActorID, _ f3.ActorID, _ f3.Message) bool {
@@ -9,18 +9,20 @@ import (
 // Against a naive algorithm, when set up with 30% of power, and a victim set with 40%,
 // it can cause one victim to decide, while others revert to the base.
 type WitholdCommit struct {
-	id   f3.ActorID
-	ntwk sim.AdversaryNetworkSink
+	id     f3.ActorID
+	ntwk   sim.AdversaryNetworkSink
+	signer f3.Signer
 	// The first victim is the target, others are those who need to confirm.
 	victims     []f3.ActorID
 	victimValue f3.ECChain
 }
 
 // A participant that never sends anything.
-func NewWitholdCommit(id f3.ActorID, ntwk sim.AdversaryNetworkSink) *WitholdCommit {
+func NewWitholdCommit(id f3.ActorID, ntwk sim.AdversaryNetworkSink, signer f3.Signer) *WitholdCommit {
 	return &WitholdCommit{
-		id:   id,
-		ntwk: ntwk,
+		id:     id,
+		ntwk:   ntwk,
+		signer: signer,
 	}
 }
 
@@ -33,33 +35,33 @@ func (w *WitholdCommit) ID() f3.ActorID {
 	return w.id
 }
 
-func (w *WitholdCommit) ReceiveCanonicalChain(_ f3.ECChain, _ f3.PowerTable, _ []byte) {
+func (w *WitholdCommit) ReceiveCanonicalChain(_ f3.ECChain) {
 }
 
 func (w *WitholdCommit) ReceiveMessage(_ *f3.GMessage) {
 }
 
-func (w *WitholdCommit) ReceiveAlarm(_ string) {
+func (w *WitholdCommit) ReceiveAlarm(_ f3.Alarm) {
 }
 
 func (w *WitholdCommit) Begin() {
 	// All victims need to see QUALITY and PREPARE in order to send their COMMIT,
 	// but only the one victim will see our COMMIT.
-	w.ntwk.BroadcastSynchronous(w.id, f3.GMessage{
+	w.broadcast(f3.GMessage{
 		Sender:   w.id,
 		Instance: 0,
 		Round:    0,
 		Step:     f3.QUALITY,
 		Value:    w.victimValue,
 	})
-	w.ntwk.BroadcastSynchronous(w.id, f3.GMessage{
+	w.broadcast(f3.GMessage{
 		Sender:   w.id,
 		Instance: 0,
 		Round:    0,
 		Step:     f3.PREPARE,
 		Value:    w.victimValue,
 	})
-	w.ntwk.BroadcastSynchronous(w.id, f3.GMessage{
+	w.broadcast(f3.GMessage{
 		Sender:   w.id,
 		Instance: 0,
 		Round:    0,
@@ -68,6 +70,12 @@ func (w *WitholdCommit) Begin() {
 	})
 }
 
+// Signs and broadcasts a message immediately.
+func (w *WitholdCommit) broadcast(msg f3.GMessage) {
+	msg.Signature = w.signer.Sign(sim.FakePubKey(w.id), msg.SignaturePayload())
+	w.ntwk.BroadcastSynchronous(w.id, msg)
+}
+
 func (w *WitholdCommit) AllowMessage(_ f3.ActorID, to f3.ActorID, msg f3.Message) bool {
 	gmsg, ok := msg.(f3.GMessage)
 	if ok {
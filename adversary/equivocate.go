@@ -0,0 +1,61 @@
+package adversary
+
+import (
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+)
+
+// This adversary sends conflicting PREPARE and COMMIT messages for two different values to all participants.
+// Honest participants should detect and report the equivocation, counting only the first value received.
+type Equivocate struct {
+	id     f3.ActorID
+	ntwk   sim.AdversaryNetworkSink
+	signer f3.Signer
+}
+
+func NewEquivocate(id f3.ActorID, ntwk sim.AdversaryNetworkSink, signer f3.Signer) *Equivocate {
+	return &Equivocate{
+		id:     id,
+		ntwk:   ntwk,
+		signer: signer,
+	}
+}
+
+func (e *Equivocate) ID() f3.ActorID {
+	return e.id
+}
+
+func (e *Equivocate) ReceiveCanonicalChain(_ f3.ECChain) {
+}
+
+func (e *Equivocate) ReceiveMessage(_ *f3.GMessage) {
+}
+
+func (e *Equivocate) ReceiveAlarm(_ f3.Alarm) {
+}
+
+// Sends QUALITY for the first value, then PREPARE and COMMIT for both values, in instance 0 round 0.
+func (e *Equivocate) Begin(first, second f3.ECChain) {
+	e.broadcast(f3.QUALITY, first)
+	e.broadcast(f3.PREPARE, first)
+	e.broadcast(f3.PREPARE, second)
+	e.broadcast(f3.COMMIT, first)
+	e.broadcast(f3.COMMIT, second)
+}
+
+// Signs and broadcasts a message immediately.
+func (e *Equivocate) broadcast(step string, value f3.ECChain) {
+	msg := f3.GMessage{
+		Sender:   e.id,
+		Instance: 0,
+		Round:    0,
+		Step:     step,
+		Value:    value,
+	}
+	msg.Signature = e.signer.Sign(sim.FakePubKey(e.id), msg.SignaturePayload())
+	e.ntwk.BroadcastSynchronous(e.id, msg)
+}
+
+func (e *Equivocate) AllowMessage(_ f3.ActorID, _ f3.ActorID, _ f3.Message) bool {
+	return true
+}
@@ -0,0 +1,242 @@
+// Package blssig implements BLS signatures over the BLS12-381 curve, for use as an f3.SignerVerifier.
+// Public keys are compressed G1 points (48 bytes) and signatures compressed G2 points (96 bytes).
+// Aggregation weights each signature and key with a coefficient derived from the full set of keys,
+// which protects aggregates of signatures over a common payload against rogue-key attacks.
+package blssig
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// Domain separation tag for hashing payloads to G2.
+const DST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_"
+
+// Size of the aggregation coefficients, in bytes.
+const coefficientSize = 16
+
+// A BLS private key, a scalar in the curve's prime order field.
+type PrivateKey struct {
+	scalar *big.Int
+}
+
+// Generates a new private key from a source of randomness.
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	order := bls12381.NewG1().Q()
+	for {
+		k, err := randInt(rand, order)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() != 0 {
+			return &PrivateKey{scalar: k}, nil
+		}
+	}
+}
+
+// Derives a private key deterministically from a seed.
+// Intended for tests and simulations; a production key must be generated from a secure random source.
+func KeyFromSeed(seed []byte) *PrivateKey {
+	order := bls12381.NewG1().Q()
+	digest := sha256.Sum256(seed)
+	k := new(big.Int).SetBytes(digest[:])
+	k.Mod(k, order)
+	if k.Sign() == 0 {
+		k.SetInt64(1)
+	}
+	return &PrivateKey{scalar: k}
+}
+
+// Returns the public key corresponding to a private key.
+func (k *PrivateKey) PublicKey() f3.PubKey {
+	g1 := bls12381.NewG1()
+	p := g1.MulScalarBig(g1.New(), g1.One(), k.scalar)
+	return g1.ToCompressed(p)
+}
+
+// Signs a payload.
+func (k *PrivateKey) Sign(msg []byte) []byte {
+	g2 := bls12381.NewG2()
+	h, err := g2.HashToCurve(msg, []byte(DST))
+	if err != nil {
+		// Hashing fails only for an over-long domain separation tag.
+		panic(err)
+	}
+	sig := g2.MulScalarBig(g2.New(), h, k.scalar)
+	return g2.ToCompressed(sig)
+}
+
+// Verifies and aggregates BLS signatures.
+// A Verifier holds no state, and is safe for concurrent use.
+type Verifier struct {
+}
+
+func NewVerifier() *Verifier {
+	return &Verifier{}
+}
+
+func (v *Verifier) Verify(pubKey f3.PubKey, msg []byte, sig []byte) bool {
+	g1 := bls12381.NewG1()
+	pk, err := decodePubKey(g1, pubKey)
+	if err != nil {
+		return false
+	}
+	return verify(pk, msg, sig)
+}
+
+func (v *Verifier) Aggregate(pubKeys []f3.PubKey, sigs [][]byte) ([]byte, error) {
+	if len(pubKeys) != len(sigs) {
+		return nil, fmt.Errorf("%d public keys but %d signatures", len(pubKeys), len(sigs))
+	}
+	if len(sigs) == 0 {
+		return nil, errors.New("no signatures to aggregate")
+	}
+	g2 := bls12381.NewG2()
+	coefficients := aggregationCoefficients(pubKeys)
+	points := make([]*bls12381.PointG2, len(sigs))
+	for i, sig := range sigs {
+		p, err := decodeSignature(g2, sig)
+		if err != nil {
+			return nil, fmt.Errorf("signature %d: %w", i, err)
+		}
+		points[i] = p
+	}
+	agg, err := g2.MultiExpBig(g2.New(), points, coefficients)
+	if err != nil {
+		return nil, err
+	}
+	return g2.ToCompressed(agg), nil
+}
+
+func (v *Verifier) VerifyAggregate(payload []byte, aggSig []byte, pubKeys []f3.PubKey) bool {
+	if len(pubKeys) == 0 {
+		return false
+	}
+	g1 := bls12381.NewG1()
+	coefficients := aggregationCoefficients(pubKeys)
+	points := make([]*bls12381.PointG1, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		p, err := decodePubKey(g1, pubKey)
+		if err != nil {
+			return false
+		}
+		points[i] = p
+	}
+	aggKey, err := g1.MultiExpBig(g1.New(), points, coefficients)
+	if err != nil {
+		return false
+	}
+	return verify(aggKey, payload, aggSig)
+}
+
+// Signs on behalf of a set of private keys, and verifies and aggregates signatures.
+// A Signer is not safe for concurrent modification of its keys.
+type Signer struct {
+	Verifier
+	// Private keys, indexed by public key.
+	keys map[string]*PrivateKey
+}
+
+func NewSigner(keys ...*PrivateKey) *Signer {
+	s := &Signer{keys: map[string]*PrivateKey{}}
+	for _, k := range keys {
+		s.AddKey(k)
+	}
+	return s
+}
+
+// Adds a private key to the signer, returning its public key.
+func (s *Signer) AddKey(key *PrivateKey) f3.PubKey {
+	pubKey := key.PublicKey()
+	s.keys[string(pubKey)] = key
+	return pubKey
+}
+
+// Signs a payload with the private key for a public key.
+// Returns nil if the signer does not hold the private key.
+func (s *Signer) Sign(sender f3.PubKey, msg []byte) []byte {
+	key, ok := s.keys[string(sender)]
+	if !ok {
+		return nil
+	}
+	return key.Sign(msg)
+}
+
+// Checks e(pk, H(msg)) == e(g1, sig).
+func verify(pk *bls12381.PointG1, msg []byte, sig []byte) bool {
+	g1 := bls12381.NewG1()
+	g2 := bls12381.NewG2()
+	s, err := decodeSignature(g2, sig)
+	if err != nil {
+		return false
+	}
+	h, err := g2.HashToCurve(msg, []byte(DST))
+	if err != nil {
+		return false
+	}
+	engine := bls12381.NewEngine()
+	engine.AddPair(pk, h)
+	engine.AddPairInv(g1.One(), s)
+	return engine.Check()
+}
+
+func decodePubKey(g1 *bls12381.G1, pubKey f3.PubKey) (*bls12381.PointG1, error) {
+	p, err := g1.FromCompressed(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	if g1.IsZero(p) || !g1.InCorrectSubgroup(p) {
+		return nil, errors.New("public key is not a valid subgroup point")
+	}
+	return p, nil
+}
+
+func decodeSignature(g2 *bls12381.G2, sig []byte) (*bls12381.PointG2, error) {
+	p, err := g2.FromCompressed(sig)
+	if err != nil {
+		return nil, err
+	}
+	if !g2.InCorrectSubgroup(p) {
+		return nil, errors.New("signature is not a valid subgroup point")
+	}
+	return p, nil
+}
+
+// Computes a coefficient for each key, from a digest of the key and the full list of keys.
+func aggregationCoefficients(pubKeys []f3.PubKey) []*big.Int {
+	all := sha256.New()
+	for _, pubKey := range pubKeys {
+		all.Write(pubKey)
+	}
+	allDigest := all.Sum(nil)
+
+	coefficients := make([]*big.Int, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		h := sha256.New()
+		h.Write(allDigest)
+		h.Write(pubKey)
+		digest := h.Sum(nil)
+		coefficients[i] = new(big.Int).SetBytes(digest[:coefficientSize])
+	}
+	return coefficients
+}
+
+// Reads a uniformly random integer in [0, max).
+func randInt(rand io.Reader, max *big.Int) (*big.Int, error) {
+	// Read 16 extra bytes, so the modular reduction has negligible bias.
+	buf := make([]byte, (max.BitLen()+7)/8+16)
+	if _, err := io.ReadFull(rand, buf); err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(buf)
+	return k.Mod(k, max), nil
+}
+
+var _ f3.SignerVerifier = (*Signer)(nil)
@@ -0,0 +1,52 @@
+package blssig
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	key, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := NewSigner()
+	pubKey := signer.AddKey(key)
+	require.Len(t, pubKey, 48)
+
+	msg := []byte("payload")
+	sig := signer.Sign(pubKey, msg)
+	require.Len(t, sig, 96)
+	require.True(t, signer.Verify(pubKey, msg, sig))
+	require.False(t, signer.Verify(pubKey, []byte("other"), sig))
+
+	other := KeyFromSeed([]byte("other"))
+	require.False(t, signer.Verify(other.PublicKey(), msg, sig))
+	require.Nil(t, signer.Sign(other.PublicKey(), msg))
+}
+
+func TestAggregate(t *testing.T) {
+	signer := NewSigner()
+	var pubKeys []f3.PubKey
+	for _, seed := range []string{"a", "b", "c"} {
+		pubKeys = append(pubKeys, signer.AddKey(KeyFromSeed([]byte(seed))))
+	}
+	payload := []byte("payload")
+	sigs := make([][]byte, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		sigs[i] = signer.Sign(pubKey, payload)
+	}
+
+	agg, err := signer.Aggregate(pubKeys, sigs)
+	require.NoError(t, err)
+	require.True(t, signer.VerifyAggregate(payload, agg, pubKeys))
+	require.False(t, signer.VerifyAggregate([]byte("other"), agg, pubKeys))
+	// A subset of keys does not verify.
+	require.False(t, signer.VerifyAggregate(payload, agg, pubKeys[:2]))
+	// Keys out of aggregation order do not verify.
+	require.False(t, signer.VerifyAggregate(payload, agg, []f3.PubKey{pubKeys[1], pubKeys[0], pubKeys[2]}))
+
+	_, err = signer.Aggregate(pubKeys, sigs[:2])
+	require.Error(t, err)
+}
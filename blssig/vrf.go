@@ -0,0 +1,9 @@
+package blssig
+
+import "github.com/filecoin-project/go-f3/f3"
+
+// Returns a VRF producing tickets as BLS signatures by the signer's keys.
+// BLS signatures are unique, so a participant's ticket is fully determined by its key and the ticket inputs.
+func NewVRF(signer *Signer) *f3.SignatureVRF {
+	return f3.NewSignatureVRF(signer, signer)
+}
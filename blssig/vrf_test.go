@@ -0,0 +1,54 @@
+package blssig
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+// Regression vectors, recorded from this implementation rather than taken from an external specification.
+// They detect any change to the tickets derived from a key, which would break compatibility between versions.
+func TestVRFRegressionVectors(t *testing.T) {
+	signer := NewSigner()
+	pubKey := signer.AddKey(KeyFromSeed([]byte("vrf")))
+	require.Equal(t, "a55a95fdf992c8a8d5c421a1225149108650f8a704debe0de632d98fa93f817d31124453b3bacc3e06e38f77e3729978",
+		hex.EncodeToString(pubKey))
+	vrf := NewVRF(signer)
+	beacon := []byte("beacon")
+
+	for _, test := range []struct {
+		instance int
+		round    int
+		ticket   string
+	}{
+		{0, 0, "85f9e7374a587c14a53a490ef69ca4cbccbbbac0804537504dd9c78cac94270a61132a18f18741216579e4b47220f81f" +
+			"0ced54923bfa52738b8ae2b0c4bce86f40ec4b04f01ae73300255481db76e79eda959bba5e876db192535b0c49febe90"},
+		{1, 2, "b00ff2c778851a0369c1784968317d0541a8eaf7ce99feb5d94268935f3697ed82d51362ed24b2d5d38e09392e99d480" +
+			"051b76534e90541d4a1ffae6307a47e0b3bde33366d7e81bf9f3ef851043283156e7d0202323086f0b784b8af1cd5828"},
+	} {
+		ticket := vrf.MakeTicket(beacon, test.instance, test.round, pubKey)
+		require.Equal(t, test.ticket, hex.EncodeToString(ticket))
+		require.True(t, vrf.VerifyTicket(beacon, test.instance, test.round, pubKey, ticket))
+	}
+}
+
+func TestVRFVerifyTicket(t *testing.T) {
+	signer := NewSigner()
+	pubKey := signer.AddKey(KeyFromSeed([]byte("a")))
+	other := signer.AddKey(KeyFromSeed([]byte("b")))
+	vrf := NewVRF(signer)
+	beacon := []byte("beacon")
+	ticket := vrf.MakeTicket(beacon, 1, 2, pubKey)
+
+	require.True(t, vrf.VerifyTicket(beacon, 1, 2, pubKey, ticket))
+	require.False(t, vrf.VerifyTicket(beacon, 1, 2, other, ticket))
+	require.False(t, vrf.VerifyTicket([]byte("other"), 1, 2, pubKey, ticket))
+	require.False(t, vrf.VerifyTicket(beacon, 2, 2, pubKey, ticket))
+	require.False(t, vrf.VerifyTicket(beacon, 1, 3, pubKey, ticket))
+
+	tampered := append(f3.Ticket{}, ticket...)
+	tampered[len(tampered)-1] ^= 1
+	require.False(t, vrf.VerifyTicket(beacon, 1, 2, pubKey, tampered))
+}
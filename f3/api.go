@@ -1,10 +1,13 @@
 package f3
 
+import "fmt"
+
 // Receives EC chain values.
 type ChainReceiver interface {
 	// Receives a chain appropriate for use as initial proposals for a Granite instance.
 	// The chain's base is assumed to be an appropriate base for the instance.
-	ReceiveCanonicalChain(chain ECChain, power PowerTable, beacon []byte)
+	// The power table and beacon for the instance are looked up from the base.
+	ReceiveCanonicalChain(chain ECChain)
 }
 
 // A consensus message.
@@ -14,9 +17,21 @@ type Message interface{}
 // Receives a Granite protocol message.
 type MessageReceiver interface {
 	// Receives a message from another participant.
-	// The message is assumed to have been validated as being sent by `msg.Sender`.
+	// The message's signature is verified against the public key of `msg.Sender`.
 	ReceiveMessage(msg *GMessage)
-	ReceiveAlarm(payload string)
+	// Receives an alarm previously set by this participant.
+	ReceiveAlarm(alarm Alarm)
+}
+
+// An alarm identifies the instance, round and phase for which it was set.
+type Alarm struct {
+	Instance int
+	Round    int
+	Phase    string
+}
+
+func (a Alarm) String() string {
+	return fmt.Sprintf("ALARM{%d}(%d %s)", a.Instance, a.Round, a.Phase)
 }
 
 // Interface which network participants must implement.
@@ -34,7 +49,9 @@ type Network interface {
 	// Returns the current network time.
 	Time() float64
 	// Sets an alarm to fire at the given timestamp.
-	SetAlarm(sender ActorID, payload string, at float64)
+	SetAlarm(sender ActorID, alarm Alarm, at float64)
+	// Cancels a pending alarm, if it has not yet fired.
+	CancelAlarm(sender ActorID, alarm Alarm)
 	// Logs a message at the "logic" level
 	Log(format string, args ...interface{})
 }
@@ -0,0 +1,58 @@
+package f3
+
+import "math/bits"
+
+// A set of non-negative indices, represented as a bit vector.
+// Bit i is the (i%8)th least significant bit of byte i/8.
+// The zero value is an empty set.
+type Bitfield []byte
+
+// Returns a bitfield with the given indices set.
+func NewBitfield(indices ...int) Bitfield {
+	var b Bitfield
+	for _, i := range indices {
+		b.Set(i)
+	}
+	return b
+}
+
+// Adds an index to the set, growing the bitfield as necessary.
+func (b *Bitfield) Set(i int) {
+	if i < 0 {
+		panic("negative bitfield index")
+	}
+	for len(*b) <= i/8 {
+		*b = append(*b, 0)
+	}
+	(*b)[i/8] |= 1 << (i % 8)
+}
+
+// Checks whether an index is in the set.
+func (b Bitfield) IsSet(i int) bool {
+	if i < 0 || i/8 >= len(b) {
+		return false
+	}
+	return b[i/8]&(1<<(i%8)) != 0
+}
+
+// Returns the number of indices in the set.
+func (b Bitfield) Count() int {
+	n := 0
+	for _, x := range b {
+		n += bits.OnesCount8(x)
+	}
+	return n
+}
+
+// Returns the indices in the set, ascending.
+func (b Bitfield) Indices() []int {
+	var indices []int
+	for i, x := range b {
+		for x != 0 {
+			j := bits.TrailingZeros8(x)
+			indices = append(indices, i*8+j)
+			x &= x - 1
+		}
+	}
+	return indices
+}
@@ -0,0 +1,73 @@
+package f3
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// A portable proof that a Granite instance decided a value.
+// The certificate carries the aggregate signature of the COMMIT messages which formed the strong quorum
+// for the decided value, which allows a client to check finality without replaying the protocol.
+type FinalityCertificate struct {
+	// The instance which decided.
+	Instance int
+	// The decided chain.
+	Value ECChain
+	// The round in which the decision was reached.
+	Round int
+	// The senders of COMMIT messages for the decided value, indexed by power table order.
+	Signers Bitfield
+	// The combined power of the signers.
+	SignersPower *big.Int
+	// Aggregate of the signers' signatures over the COMMIT payload, in power table order.
+	Signature []byte
+}
+
+// Returns the finalised tipset, the head of the decided chain.
+func (c *FinalityCertificate) Head() *TipSet {
+	return c.Value.Head()
+}
+
+// Returns the IDs of the signers, in power table order.
+func (c *FinalityCertificate) SignerIDs(powerTable PowerTable) ([]ActorID, error) {
+	indices := c.Signers.Indices()
+	ids := make([]ActorID, len(indices))
+	for j, idx := range indices {
+		if idx >= len(powerTable.Entries) {
+			return nil, fmt.Errorf("signer index %d out of range", idx)
+		}
+		ids[j] = powerTable.Entries[idx].ID
+	}
+	return ids, nil
+}
+
+// Checks that the certificate is signed by a strong quorum of the power table.
+func (c *FinalityCertificate) Verify(powerTable PowerTable, aggregator Aggregator) error {
+	if c.Value.IsZero() {
+		return fmt.Errorf("certificate for bottom")
+	}
+	power, pubKeys, err := signersPower(powerTable, c.Signers)
+	if err != nil {
+		return err
+	}
+	if c.SignersPower == nil || power.Cmp(c.SignersPower) != 0 {
+		return fmt.Errorf("signers power %d does not match claimed %d", power, c.SignersPower)
+	}
+	if !IsStrongQuorum(power, powerTable.Total) {
+		return fmt.Errorf("signers power %d is not a strong quorum of %d", power, powerTable.Total)
+	}
+	payload := SignaturePayload(c.Instance, c.Round, COMMIT, c.Value)
+	if !aggregator.VerifyAggregate(payload, c.Signature, pubKeys) {
+		return fmt.Errorf("invalid aggregate signature")
+	}
+	return nil
+}
+
+// Returns a justification comprising the certificate's COMMIT quorum, as carried by a DECIDE message.
+func (c *FinalityCertificate) justification() *Justification {
+	return &Justification{Round: c.Round, Step: COMMIT, Value: c.Value, Signers: c.Signers, Signature: c.Signature}
+}
+
+func (c *FinalityCertificate) String() string {
+	return fmt.Sprintf("CERT{%d}(%d %s, %d signers, power %d)", c.Instance, c.Round, &c.Value, c.Signers.Count(), c.SignersPower)
+}
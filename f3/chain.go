@@ -11,7 +11,7 @@ type TipSet struct {
 	// The epoch of the blocks in the tipset.
 	Epoch int
 	// The CID of the tipset.
-	// TODO: define how this is computed in terms of the block header CIDs.
+	// This is computed from the tipset's block header CIDs by TipSetKeyCID.
 	CID CID
 	// The EC consensus weight of the tipset.
 	Weight uint
@@ -30,7 +30,7 @@ func NewTipSet(epoch int, cid CID, weight uint) TipSet {
 // Note that the real weight function breaks ties with VRF tickets.
 func (t *TipSet) Compare(other *TipSet) int {
 	if t.Weight == other.Weight {
-		return strings.Compare(t.CID, other.CID)
+		return CompareCIDs(t.CID, other.CID)
 	} else if t.Weight < other.Weight {
 		return -1
 	}
@@ -46,7 +46,7 @@ func (t *TipSet) Eq(other *TipSet) bool {
 
 func (t *TipSet) String() string {
 	var b strings.Builder
-	b.WriteString(t.CID)
+	b.WriteString(t.CID.String())
 	b.WriteString("@")
 	b.WriteString(strconv.Itoa(t.Epoch))
 	return b.String()
@@ -95,10 +95,10 @@ func (c ECChain) Head() *TipSet {
 	return &c[len(c)-1]
 }
 
-// Returns the CID of the head tipset, or empty string for a zero value
+// Returns the CID of the head tipset, or the zero CID for a zero value
 func (c ECChain) HeadCIDOrZero() CID {
 	if c.IsZero() {
-		return ""
+		return ZeroCID
 	}
 	return c.Head().CID
 }
@@ -112,10 +112,13 @@ func (c ECChain) BaseChain() ECChain {
 // Returns a new chain extending this chain with one tipset.
 // The new tipset is given an epoch and weight one greater than the previous head.
 func (c ECChain) Extend(cid CID) ECChain {
-	return append(c, TipSet{
-		Epoch:  c[0].Epoch + 1,
+	head := c.Head()
+	extended := make(ECChain, len(c), len(c)+1)
+	copy(extended, c)
+	return append(extended, TipSet{
+		Epoch:  head.Epoch + 1,
 		CID:    cid,
-		Weight: c[0].Weight + 1,
+		Weight: head.Weight + 1,
 	})
 }
 
@@ -127,7 +130,11 @@ func (c ECChain) Prefix(to int) ECChain {
 }
 
 // Compares two ECChains for equality.
+// All zero values (bottom) are equal, whether nil or empty.
 func (c ECChain) Eq(other ECChain) bool {
+	if c.IsZero() || other.IsZero() {
+		return c.IsZero() && other.IsZero()
+	}
 	return reflect.DeepEqual(c, other)
 }
 
@@ -176,6 +183,17 @@ func (c ECChain) HasTipset(t *TipSet) bool {
 	return false
 }
 
+// Returns the part of this chain from some tipset (as its base) to the head.
+// Returns a zero value if the tipset is not in the chain.
+func (c ECChain) From(t *TipSet) ECChain {
+	for i := range c {
+		if c[i].Eq(t) {
+			return c[i:]
+		}
+	}
+	return nil
+}
+
 func (c ECChain) String() string {
 	var b strings.Builder
 	b.WriteString("[")
@@ -0,0 +1,25 @@
+package f3_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainExtend(t *testing.T) {
+	base := f3.NewChain(f3.NewTipSet(10, f3.CIDOf([]byte("base")), 5))
+	a := base.Extend(f3.CIDOf([]byte("a")))
+	b := a.Extend(f3.CIDOf([]byte("b")))
+
+	// Each tipset follows the head, not the base.
+	require.Equal(t, 12, b.Head().Epoch)
+	require.Equal(t, uint(7), b.Head().Weight)
+	require.True(t, b.HasPrefix(a))
+
+	// Extending a chain twice gives independent chains.
+	c := a.Extend(f3.CIDOf([]byte("c")))
+	require.Equal(t, f3.CIDOf([]byte("b")), b.Head().CID)
+	require.Equal(t, f3.CIDOf([]byte("c")), c.Head().CID)
+	require.Len(t, a, 2)
+}
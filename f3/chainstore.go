@@ -0,0 +1,45 @@
+package f3
+
+import "fmt"
+
+// Provides access to the tipsets in the local Expected Consensus chain store.
+// This is the seam through which a node exposes its chain database to F3.
+type ChainStore interface {
+	// Returns the tipset with some CID, or an error if it is not in the store.
+	GetTipSet(cid CID) (TipSet, error)
+	// Returns the tipset at some epoch in the local heaviest chain,
+	// or an error if there is none (including for a null round).
+	GetTipSetByEpoch(epoch int) (TipSet, error)
+	// Checks whether one tipset is a strict ancestor of another.
+	// Returns an error if either tipset is not in the store.
+	IsAncestor(ancestor CID, descendant CID) (bool, error)
+}
+
+// Checks whether a chain is in a chain store's heaviest chain: each tipset is the heaviest chain's tipset
+// at its epoch, and the heaviest chain has no tipset at any epoch between consecutive tipsets,
+// so that each tipset is the parent of the next.
+// Returns false for a zero value, and false with an error if a tipset of the chain can't be found.
+func isInHeaviestChain(store ChainStore, chain ECChain) (bool, error) {
+	if chain.IsZero() {
+		return false, nil
+	}
+	for j := range chain {
+		heaviest, err := store.GetTipSetByEpoch(chain[j].Epoch)
+		if err != nil {
+			return false, fmt.Errorf("looking up %s: %w", &chain[j], err)
+		}
+		if !heaviest.Eq(&chain[j]) {
+			return false, nil
+		}
+		if j > 0 {
+			// The intervening epochs must be null rounds in the heaviest chain.
+			// The loop ends at the first tipset found, so is bounded by the null rounds in the store.
+			for epoch := chain[j-1].Epoch + 1; epoch < chain[j].Epoch; epoch++ {
+				if _, err := store.GetTipSetByEpoch(epoch); err == nil {
+					return false, nil
+				}
+			}
+		}
+	}
+	return true, nil
+}
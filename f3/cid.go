@@ -0,0 +1,76 @@
+package f3
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// A content identifier, self-describing its codec and multihash.
+// The zero value is undefined, and identifies no content.
+type CID = cid.Cid
+
+// The undefined CID, used as the head CID of the bottom chain.
+var ZeroCID = cid.Undef
+
+// Builds CIDs for tipset keys: CIDv1, DAG-CBOR codec, BLAKE2b-256 multihash.
+var cidBuilder = cid.V1Builder{Codec: cid.DagCBOR, MhType: multihash.BLAKE2B_MIN + 31}
+
+// Computes the CID of some data, as DAG-CBOR content hashed with BLAKE2b-256.
+func CIDOf(data []byte) CID {
+	c, err := cidBuilder.Sum(data)
+	if err != nil {
+		// Summing fails only for an unknown hash function.
+		panic(err)
+	}
+	return c
+}
+
+// Parses a CID from its string representation.
+func ParseCID(s string) (CID, error) {
+	c, err := cid.Parse(s)
+	if err != nil {
+		return ZeroCID, fmt.Errorf("invalid CID %q: %w", s, err)
+	}
+	return c, nil
+}
+
+// Parses and validates a CID from its binary representation.
+// The data must comprise exactly one CID.
+func CIDFromBytes(data []byte) (CID, error) {
+	n, c, err := cid.CidFromBytes(data)
+	if err != nil {
+		return ZeroCID, fmt.Errorf("invalid CID: %w", err)
+	}
+	if n != len(data) {
+		return ZeroCID, fmt.Errorf("invalid CID: %d trailing bytes", len(data)-n)
+	}
+	return c, nil
+}
+
+// Compares two CIDs by their binary representation.
+func CompareCIDs(a, b CID) int {
+	return bytes.Compare(a.Bytes(), b.Bytes())
+}
+
+// Derives the CID identifying a tipset from the CIDs of its block headers.
+// The block CIDs must be in the tipset's canonical block order.
+// The tipset key is the concatenation of the block CIDs' binary representations,
+// and its CID is that of the key's encoding as a CBOR byte string.
+func TipSetKeyCID(blocks []CID) (CID, error) {
+	if len(blocks) == 0 {
+		return ZeroCID, fmt.Errorf("tipset has no blocks")
+	}
+	var key []byte
+	for _, b := range blocks {
+		if !b.Defined() {
+			return ZeroCID, fmt.Errorf("undefined block CID")
+		}
+		key = append(key, b.Bytes()...)
+	}
+	var w cborWriter
+	w.writeBytes(key)
+	return CIDOf(w.buf.Bytes()), nil
+}
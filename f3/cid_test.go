@@ -0,0 +1,48 @@
+package f3_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIDRoundTrip(t *testing.T) {
+	c := f3.CIDOf([]byte("block"))
+	parsed, err := f3.ParseCID(c.String())
+	require.NoError(t, err)
+	require.Equal(t, c, parsed)
+
+	fromBytes, err := f3.CIDFromBytes(c.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, c, fromBytes)
+	require.Equal(t, 0, f3.CompareCIDs(c, fromBytes))
+
+	_, err = f3.CIDFromBytes(append(c.Bytes(), 0))
+	require.Error(t, err)
+	_, err = f3.CIDFromBytes([]byte("block"))
+	require.Error(t, err)
+	_, err = f3.ParseCID("block")
+	require.Error(t, err)
+}
+
+func TestTipSetKeyCID(t *testing.T) {
+	a := f3.CIDOf([]byte("a"))
+	b := f3.CIDOf([]byte("b"))
+
+	ab, err := f3.TipSetKeyCID([]f3.CID{a, b})
+	require.NoError(t, err)
+	again, err := f3.TipSetKeyCID([]f3.CID{a, b})
+	require.NoError(t, err)
+	require.Equal(t, ab, again)
+
+	// Block order is significant.
+	ba, err := f3.TipSetKeyCID([]f3.CID{b, a})
+	require.NoError(t, err)
+	require.NotEqual(t, ab, ba)
+
+	_, err = f3.TipSetKeyCID(nil)
+	require.Error(t, err)
+	_, err = f3.TipSetKeyCID([]f3.CID{a, f3.ZeroCID})
+	require.Error(t, err)
+}
@@ -0,0 +1,798 @@
+package f3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"unicode/utf8"
+)
+
+// Binary wire encoding of protocol values.
+//
+// Values are encoded as deterministic CBOR: definite lengths, minimal-length integer headers,
+// and structs as arrays of fields in declaration order, so equal values always have equal encodings.
+// A top-level encoding is a two-element array of the encoding version followed by the value.
+// Decoding is strict: it rejects any encoding that is not exactly what the encoder would produce.
+
+// The current version of the wire encoding.
+const EncodingVersion = 1
+
+// Limits enforced when encoding and decoding.
+const (
+	// Maximum size of a top-level encoding.
+	MaxEncodingSize = 1 << 20
+	// Maximum length of a byte or text string, other than a bitfield.
+	MaxEncodedBytesLength = 1 << 12
+	// Maximum length in bytes of a bitfield of signers, enough to index every power table entry.
+	MaxEncodedBitfieldLength = MaxEncodedPowerTableEntries / 8
+	// Maximum number of tipsets in an encoded chain.
+	MaxEncodedChainLength = 1 << 10
+	// Maximum number of entries in an encoded power table.
+	MaxEncodedPowerTableEntries = 1 << 16
+	// Maximum length in bytes of an encoded power value.
+	MaxEncodedPowerLength = 32
+)
+
+// Error returned (wrapped) for any malformed encoding.
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+)
+
+///// Top-level encodings /////
+
+func (m *GMessage) MarshalBinary() ([]byte, error) {
+	return marshalVersioned(m.encode)
+}
+
+func (m *GMessage) UnmarshalBinary(data []byte) error {
+	return unmarshalVersioned(data, m.decode)
+}
+
+func (c ECChain) MarshalBinary() ([]byte, error) {
+	return marshalVersioned(c.encode)
+}
+
+func (c *ECChain) UnmarshalBinary(data []byte) error {
+	return unmarshalVersioned(data, c.decode)
+}
+
+func (t *TipSet) MarshalBinary() ([]byte, error) {
+	return marshalVersioned(t.encode)
+}
+
+func (t *TipSet) UnmarshalBinary(data []byte) error {
+	return unmarshalVersioned(data, t.decode)
+}
+
+func (p *PowerTable) MarshalBinary() ([]byte, error) {
+	return marshalVersioned(p.encode)
+}
+
+func (p *PowerTable) UnmarshalBinary(data []byte) error {
+	return unmarshalVersioned(data, p.decode)
+}
+
+func (t Ticket) MarshalBinary() ([]byte, error) {
+	return marshalVersioned(func(w *cborWriter) error {
+		return w.writeBytes(t)
+	})
+}
+
+func (t *Ticket) UnmarshalBinary(data []byte) error {
+	return unmarshalVersioned(data, func(r *cborReader) error {
+		b, err := r.readBytes()
+		*t = b
+		return err
+	})
+}
+
+func (c *FinalityCertificate) MarshalBinary() ([]byte, error) {
+	return marshalVersioned(c.encode)
+}
+
+func (c *FinalityCertificate) UnmarshalBinary(data []byte) error {
+	return unmarshalVersioned(data, c.decode)
+}
+
+func (e *JournalEntry) MarshalBinary() ([]byte, error) {
+	return marshalVersioned(e.encode)
+}
+
+func (e *JournalEntry) UnmarshalBinary(data []byte) error {
+	return unmarshalVersioned(data, e.decode)
+}
+
+func marshalVersioned(encode func(w *cborWriter) error) ([]byte, error) {
+	var w cborWriter
+	w.writeHeader(cborArray, 2)
+	w.writeUint(EncodingVersion)
+	if err := encode(&w); err != nil {
+		return nil, err
+	}
+	if w.buf.Len() > MaxEncodingSize {
+		return nil, fmt.Errorf("encoding size %d exceeds maximum %d", w.buf.Len(), MaxEncodingSize)
+	}
+	return w.buf.Bytes(), nil
+}
+
+func unmarshalVersioned(data []byte, decode func(r *cborReader) error) error {
+	if len(data) > MaxEncodingSize {
+		return fmt.Errorf("%w: size %d exceeds maximum %d", ErrInvalidEncoding, len(data), MaxEncodingSize)
+	}
+	r := cborReader{data: data}
+	if err := r.readArrayHeader(2); err != nil {
+		return err
+	}
+	version, err := r.readUint()
+	if err != nil {
+		return err
+	}
+	if version != EncodingVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidEncoding, version)
+	}
+	if err := decode(&r); err != nil {
+		return err
+	}
+	return r.done()
+}
+
+///// Value encodings /////
+
+func (m *GMessage) encode(w *cborWriter) error {
+	w.writeHeader(cborArray, 8)
+	w.writeUint(uint64(m.Sender))
+	if err := w.writeIndex(m.Instance); err != nil {
+		return err
+	}
+	if err := w.writeIndex(m.Round); err != nil {
+		return err
+	}
+	if err := w.writeText(m.Step); err != nil {
+		return err
+	}
+	if err := w.writeBytes(m.Ticket); err != nil {
+		return err
+	}
+	if err := m.Value.encode(w); err != nil {
+		return err
+	}
+	if err := w.writeBytes(m.Signature); err != nil {
+		return err
+	}
+	return m.Justification.encode(w)
+}
+
+func (m *GMessage) decode(r *cborReader) error {
+	var err error
+	if err = r.readArrayHeader(8); err != nil {
+		return err
+	}
+	var sender uint64
+	if sender, err = r.readUint(); err != nil {
+		return err
+	}
+	m.Sender = ActorID(sender)
+	if m.Instance, err = r.readIndex(); err != nil {
+		return err
+	}
+	if m.Round, err = r.readIndex(); err != nil {
+		return err
+	}
+	if m.Step, err = r.readText(); err != nil {
+		return err
+	}
+	if m.Ticket, err = r.readBytes(); err != nil {
+		return err
+	}
+	if err = m.Value.decode(r); err != nil {
+		return err
+	}
+	if m.Signature, err = r.readBytes(); err != nil {
+		return err
+	}
+	if m.Justification, err = decodeJustification(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Encodes an optional justification as an array of its fields, or an empty array if nil.
+func (j *Justification) encode(w *cborWriter) error {
+	if j == nil {
+		w.writeHeader(cborArray, 0)
+		return nil
+	}
+	w.writeHeader(cborArray, 5)
+	if err := w.writeIndex(j.Round); err != nil {
+		return err
+	}
+	if err := w.writeText(j.Step); err != nil {
+		return err
+	}
+	if err := j.Value.encode(w); err != nil {
+		return err
+	}
+	if err := w.writeBitfield(j.Signers); err != nil {
+		return err
+	}
+	return w.writeBytes(j.Signature)
+}
+
+func decodeJustification(r *cborReader) (*Justification, error) {
+	n, err := r.readArrayLength(5)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if n != 5 {
+		return nil, fmt.Errorf("%w: expected array of length 5, got %d", ErrInvalidEncoding, n)
+	}
+	var j Justification
+	if j.Round, err = r.readIndex(); err != nil {
+		return nil, err
+	}
+	if j.Step, err = r.readText(); err != nil {
+		return nil, err
+	}
+	if err = j.Value.decode(r); err != nil {
+		return nil, err
+	}
+	if j.Signers, err = r.readBitfield(); err != nil {
+		return nil, err
+	}
+	if j.Signature, err = r.readBytes(); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (c *FinalityCertificate) encode(w *cborWriter) error {
+	w.writeHeader(cborArray, 6)
+	if err := w.writeIndex(c.Instance); err != nil {
+		return err
+	}
+	if err := c.Value.encode(w); err != nil {
+		return err
+	}
+	if err := w.writeIndex(c.Round); err != nil {
+		return err
+	}
+	if err := w.writeBitfield(c.Signers); err != nil {
+		return err
+	}
+	if err := w.writePower(c.SignersPower); err != nil {
+		return err
+	}
+	return w.writeBytes(c.Signature)
+}
+
+func (c *FinalityCertificate) decode(r *cborReader) error {
+	var err error
+	if err = r.readArrayHeader(6); err != nil {
+		return err
+	}
+	if c.Instance, err = r.readIndex(); err != nil {
+		return err
+	}
+	if err = c.Value.decode(r); err != nil {
+		return err
+	}
+	if c.Round, err = r.readIndex(); err != nil {
+		return err
+	}
+	if c.Signers, err = r.readBitfield(); err != nil {
+		return err
+	}
+	if c.SignersPower, err = r.readPower(); err != nil {
+		return err
+	}
+	if c.Signature, err = r.readBytes(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Encodes a journal entry as an array of its fields.
+// The optional message and certificate are each encoded as an array of zero or one elements.
+func (e *JournalEntry) encode(w *cborWriter) error {
+	w.writeHeader(cborArray, 7)
+	if err := w.writeText(e.Kind); err != nil {
+		return err
+	}
+	if err := w.writeIndex(e.Instance); err != nil {
+		return err
+	}
+	for _, chain := range []ECChain{e.Input, e.Proposal, e.Value} {
+		if err := chain.encode(w); err != nil {
+			return err
+		}
+	}
+	if e.Message == nil {
+		w.writeHeader(cborArray, 0)
+	} else {
+		w.writeHeader(cborArray, 1)
+		if err := e.Message.encode(w); err != nil {
+			return err
+		}
+	}
+	if e.Certificate == nil {
+		w.writeHeader(cborArray, 0)
+		return nil
+	}
+	w.writeHeader(cborArray, 1)
+	return e.Certificate.encode(w)
+}
+
+func (e *JournalEntry) decode(r *cborReader) error {
+	var err error
+	if err = r.readArrayHeader(7); err != nil {
+		return err
+	}
+	if e.Kind, err = r.readText(); err != nil {
+		return err
+	}
+	if e.Instance, err = r.readIndex(); err != nil {
+		return err
+	}
+	for _, chain := range []*ECChain{&e.Input, &e.Proposal, &e.Value} {
+		if err = chain.decode(r); err != nil {
+			return err
+		}
+	}
+	n, err := r.readArrayLength(1)
+	if err != nil {
+		return err
+	}
+	if n == 1 {
+		e.Message = &GMessage{}
+		if err = e.Message.decode(r); err != nil {
+			return err
+		}
+	}
+	if n, err = r.readArrayLength(1); err != nil {
+		return err
+	}
+	if n == 1 {
+		e.Certificate = &FinalityCertificate{}
+		if err = e.Certificate.decode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c ECChain) encode(w *cborWriter) error {
+	if len(c) > MaxEncodedChainLength {
+		return fmt.Errorf("chain length %d exceeds maximum %d", len(c), MaxEncodedChainLength)
+	}
+	w.writeHeader(cborArray, uint64(len(c)))
+	for i := range c {
+		if err := c[i].encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ECChain) decode(r *cborReader) error {
+	n, err := r.readArrayLength(MaxEncodedChainLength)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Bottom.
+		*c = nil
+		return nil
+	}
+	chain := make(ECChain, n)
+	for i := range chain {
+		if err := chain[i].decode(r); err != nil {
+			return err
+		}
+	}
+	*c = chain
+	return nil
+}
+
+func (t *TipSet) encode(w *cborWriter) error {
+	w.writeHeader(cborArray, 3)
+	w.writeInt(int64(t.Epoch))
+	if err := w.writeBytes(t.CID.Bytes()); err != nil {
+		return err
+	}
+	w.writeUint(uint64(t.Weight))
+	return nil
+}
+
+func (t *TipSet) decode(r *cborReader) error {
+	if err := r.readArrayHeader(3); err != nil {
+		return err
+	}
+	epoch, err := r.readInt()
+	if err != nil {
+		return err
+	}
+	if epoch < math.MinInt || epoch > math.MaxInt {
+		return fmt.Errorf("%w: epoch %d out of range", ErrInvalidEncoding, epoch)
+	}
+	cidBytes, err := r.readBytes()
+	if err != nil {
+		return err
+	}
+	cid, err := CIDFromBytes(cidBytes)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEncoding, err)
+	}
+	weight, err := r.readUint()
+	if err != nil {
+		return err
+	}
+	if weight > math.MaxUint {
+		return fmt.Errorf("%w: weight %d out of range", ErrInvalidEncoding, weight)
+	}
+	*t = TipSet{Epoch: int(epoch), CID: cid, Weight: uint(weight)}
+	return nil
+}
+
+func (p *PowerTable) encode(w *cborWriter) error {
+	if len(p.Entries) > MaxEncodedPowerTableEntries {
+		return fmt.Errorf("power table size %d exceeds maximum %d", len(p.Entries), MaxEncodedPowerTableEntries)
+	}
+	w.writeHeader(cborArray, uint64(len(p.Entries)))
+	for _, e := range p.Entries {
+		w.writeHeader(cborArray, 3)
+		w.writeUint(uint64(e.ID))
+		if err := w.writePower(e.Power); err != nil {
+			return err
+		}
+		if err := w.writeBytes(e.PubKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PowerTable) decode(r *cborReader) error {
+	n, err := r.readArrayLength(MaxEncodedPowerTableEntries)
+	if err != nil {
+		return err
+	}
+	table := NewPowerTable()
+	for i := 0; i < n; i++ {
+		if err := r.readArrayHeader(3); err != nil {
+			return err
+		}
+		id, err := r.readUint()
+		if err != nil {
+			return err
+		}
+		power, err := r.readPower()
+		if err != nil {
+			return err
+		}
+		pubKey, err := r.readBytes()
+		if err != nil {
+			return err
+		}
+		if table.Has(ActorID(id)) {
+			return fmt.Errorf("%w: duplicate power table entry %d", ErrInvalidEncoding, id)
+		}
+		table.Add(ActorID(id), power, pubKey)
+	}
+	*p = table
+	return nil
+}
+
+// Encodes the payload signed for a message with some instance, round, step and value.
+// The payload is a domain separation prefix followed by the CBOR array [instance, round, step, value].
+// A payload is only signed, never decoded, so the decoding limits do not apply.
+func SignaturePayload(instance int, round int, step string, value ECChain) []byte {
+	var w cborWriter
+	w.buf.WriteString(signaturePayloadPrefix)
+	w.writeHeader(cborArray, 4)
+	w.writeInt(int64(instance))
+	w.writeInt(int64(round))
+	w.writeString(cborText, []byte(step))
+	w.writeHeader(cborArray, uint64(len(value)))
+	for i := range value {
+		_ = value[i].encode(&w)
+	}
+	return w.buf.Bytes()
+}
+
+const signaturePayloadPrefix = "GPBFT:"
+
+// Encodes the payload signed to produce a VRF ticket for some beacon, instance and round.
+// The payload is a domain separation prefix, distinct from that of message payloads,
+// followed by the CBOR array [beacon, instance, round].
+func VRFPayload(beacon []byte, instance int, round int) []byte {
+	var w cborWriter
+	w.buf.WriteString(vrfPayloadPrefix)
+	w.writeHeader(cborArray, 3)
+	w.writeString(cborBytes, beacon)
+	w.writeInt(int64(instance))
+	w.writeInt(int64(round))
+	return w.buf.Bytes()
+}
+
+const vrfPayloadPrefix = "VRF:"
+
+///// CBOR primitives /////
+
+type cborWriter struct {
+	buf bytes.Buffer
+}
+
+// Writes a major type and argument with the shortest possible header.
+func (w *cborWriter) writeHeader(major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		w.buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		w.buf.WriteByte(major | 24)
+		w.buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.buf.WriteByte(major | 25)
+		_ = binary.Write(&w.buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		w.buf.WriteByte(major | 26)
+		_ = binary.Write(&w.buf, binary.BigEndian, uint32(n))
+	default:
+		w.buf.WriteByte(major | 27)
+		_ = binary.Write(&w.buf, binary.BigEndian, n)
+	}
+}
+
+func (w *cborWriter) writeUint(n uint64) {
+	w.writeHeader(cborUint, n)
+}
+
+func (w *cborWriter) writeInt(n int64) {
+	if n >= 0 {
+		w.writeHeader(cborUint, uint64(n))
+	} else {
+		w.writeHeader(cborNegInt, uint64(-(n + 1)))
+	}
+}
+
+// Writes a non-negative int, such as an instance or round number.
+func (w *cborWriter) writeIndex(n int) error {
+	if n < 0 {
+		return fmt.Errorf("negative value %d", n)
+	}
+	w.writeUint(uint64(n))
+	return nil
+}
+
+func (w *cborWriter) writeBytes(b []byte) error {
+	return w.writeLimitedString(cborBytes, b, MaxEncodedBytesLength)
+}
+
+func (w *cborWriter) writeText(s string) error {
+	return w.writeLimitedString(cborText, []byte(s), MaxEncodedBytesLength)
+}
+
+func (w *cborWriter) writeBitfield(b Bitfield) error {
+	return w.writeLimitedString(cborBytes, b, MaxEncodedBitfieldLength)
+}
+
+// Writes a byte or text string, failing if it is longer than the decoder accepts.
+func (w *cborWriter) writeLimitedString(major byte, b []byte, max int) error {
+	if len(b) > max {
+		return fmt.Errorf("length %d exceeds maximum %d", len(b), max)
+	}
+	w.writeString(major, b)
+	return nil
+}
+
+// Writes a byte or text string of any length.
+func (w *cborWriter) writeString(major byte, b []byte) {
+	w.writeHeader(major, uint64(len(b)))
+	w.buf.Write(b)
+}
+
+// Writes a non-negative power value as a byte string of its minimal big-endian magnitude.
+// Zero is the empty string.
+func (w *cborWriter) writePower(p *big.Int) error {
+	if p.Sign() < 0 {
+		return fmt.Errorf("negative power %s", p)
+	}
+	b := p.Bytes()
+	if len(b) > MaxEncodedPowerLength {
+		return fmt.Errorf("power length %d exceeds maximum %d", len(b), MaxEncodedPowerLength)
+	}
+	return w.writeBytes(b)
+}
+
+type cborReader struct {
+	data []byte
+	pos  int
+}
+
+// Reads a header, rejecting indefinite lengths and non-minimal encodings.
+func (r *cborReader) readHeader() (byte, uint64, error) {
+	if r.pos >= len(r.data) {
+		return 0, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidEncoding)
+	}
+	initial := r.data[r.pos]
+	r.pos++
+	major := initial >> 5
+	info := initial & 0x1f
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	// The size of the argument, and the minimum value which requires that size.
+	var size int
+	var minimum uint64
+	switch info {
+	case 24:
+		size, minimum = 1, 24
+	case 25:
+		size, minimum = 2, math.MaxUint8+1
+	case 26:
+		size, minimum = 4, math.MaxUint16+1
+	case 27:
+		size, minimum = 8, math.MaxUint32+1
+	default:
+		return 0, 0, fmt.Errorf("%w: unsupported additional info %d", ErrInvalidEncoding, info)
+	}
+	if len(r.data)-r.pos < size {
+		return 0, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidEncoding)
+	}
+	var n uint64
+	for _, b := range r.data[r.pos : r.pos+size] {
+		n = n<<8 | uint64(b)
+	}
+	r.pos += size
+	if n < minimum {
+		return 0, 0, fmt.Errorf("%w: non-minimal integer encoding", ErrInvalidEncoding)
+	}
+	return major, n, nil
+}
+
+func (r *cborReader) readExpected(expected byte) (uint64, error) {
+	major, n, err := r.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	if major != expected {
+		return 0, fmt.Errorf("%w: expected major type %d, found %d", ErrInvalidEncoding, expected, major)
+	}
+	return n, nil
+}
+
+func (r *cborReader) readUint() (uint64, error) {
+	return r.readExpected(cborUint)
+}
+
+func (r *cborReader) readInt() (int64, error) {
+	major, n, err := r.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: integer out of range", ErrInvalidEncoding)
+	}
+	switch major {
+	case cborUint:
+		return int64(n), nil
+	case cborNegInt:
+		return -1 - int64(n), nil
+	default:
+		return 0, fmt.Errorf("%w: expected integer, found major type %d", ErrInvalidEncoding, major)
+	}
+}
+
+func (r *cborReader) readIndex() (int, error) {
+	n, err := r.readUint()
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt {
+		return 0, fmt.Errorf("%w: value %d out of range", ErrInvalidEncoding, n)
+	}
+	return int(n), nil
+}
+
+// Reads a byte string, returning nil for an empty string.
+func (r *cborReader) readBytes() ([]byte, error) {
+	return r.readBytesLimited(MaxEncodedBytesLength)
+}
+
+// Reads a bitfield, returning nil for an empty string.
+func (r *cborReader) readBitfield() (Bitfield, error) {
+	return r.readBytesLimited(MaxEncodedBitfieldLength)
+}
+
+func (r *cborReader) readBytesLimited(max int) ([]byte, error) {
+	b, err := r.readString(cborBytes, max)
+	if err != nil || len(b) == 0 {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+func (r *cborReader) readText() (string, error) {
+	b, err := r.readString(cborText, MaxEncodedBytesLength)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", fmt.Errorf("%w: invalid UTF-8 text", ErrInvalidEncoding)
+	}
+	return string(b), nil
+}
+
+// Reads a power value, rejecting leading zero bytes.
+func (r *cborReader) readPower() (*big.Int, error) {
+	b, err := r.readString(cborBytes, MaxEncodedPowerLength)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 && b[0] == 0 {
+		return nil, fmt.Errorf("%w: non-minimal power", ErrInvalidEncoding)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Reads a byte or text string of length up to some maximum.
+func (r *cborReader) readString(major byte, max int) ([]byte, error) {
+	n, err := r.readExpected(major)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(max) {
+		return nil, fmt.Errorf("%w: length %d exceeds maximum %d", ErrInvalidEncoding, n, max)
+	}
+	if uint64(len(r.data)-r.pos) < n {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidEncoding)
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// Reads an array header with an exact length.
+func (r *cborReader) readArrayHeader(length int) error {
+	n, err := r.readExpected(cborArray)
+	if err != nil {
+		return err
+	}
+	if n != uint64(length) {
+		return fmt.Errorf("%w: expected array of length %d, found %d", ErrInvalidEncoding, length, n)
+	}
+	return nil
+}
+
+// Reads an array header with length up to some maximum.
+func (r *cborReader) readArrayLength(max int) (int, error) {
+	n, err := r.readExpected(cborArray)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(max) {
+		return 0, fmt.Errorf("%w: array length %d exceeds maximum %d", ErrInvalidEncoding, n, max)
+	}
+	return int(n), nil
+}
+
+// Checks that all data has been consumed.
+func (r *cborReader) done() error {
+	if r.pos != len(r.data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidEncoding, len(r.data)-r.pos)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package f3_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGMessageRoundTrip(t *testing.T) {
+	base := f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1)
+	chain := f3.NewChain(base, f3.NewTipSet(101, f3.CIDOf([]byte("a")), 2), f3.NewTipSet(103, f3.CIDOf([]byte("b")), 3))
+	for _, msg := range []*f3.GMessage{
+		{Sender: 1, Instance: 2, Round: 3, Step: f3.CONVERGE, Ticket: []byte("ticket"), Value: chain, Signature: []byte("sig")},
+		{Sender: 1, Instance: 0, Round: 0, Step: f3.COMMIT, Value: nil, Signature: []byte("sig")},
+		{Sender: 2, Instance: 1, Round: 1, Step: f3.COMMIT, Value: chain, Signature: []byte("sig"),
+			Justification: &f3.Justification{Round: 1, Step: f3.PREPARE, Value: chain,
+				Signers: f3.NewBitfield(0, 2, 9), Signature: []byte("agg")}},
+		{Sender: 2, Instance: 1, Round: 2, Step: f3.CONVERGE, Value: chain, Signature: []byte("sig"),
+			Justification: &f3.Justification{Round: 1, Step: f3.COMMIT, Value: nil,
+				Signers: f3.NewBitfield(1), Signature: []byte("agg")}},
+	} {
+		data, err := msg.MarshalBinary()
+		require.NoError(t, err)
+		var decoded f3.GMessage
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		require.Equal(t, *msg, decoded)
+
+		// Encoding is deterministic.
+		again, err := decoded.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, data, again)
+	}
+}
+
+func TestChainEncoding(t *testing.T) {
+	genesis := f3.CIDOf([]byte("genesis"))
+	a := f3.CIDOf([]byte("a"))
+	chain := f3.NewChain(f3.NewTipSet(-1, genesis, 0), f3.NewTipSet(1, a, 300))
+	data, err := chain.MarshalBinary()
+	require.NoError(t, err)
+	// [1, [[-1, h'<genesis>', 0], [1, h'<a>', 300]]]
+	var expected []byte
+	expected = append(expected, 0x82, 0x01, 0x82, 0x83, 0x20, 0x58, byte(len(genesis.Bytes())))
+	expected = append(expected, genesis.Bytes()...)
+	expected = append(expected, 0x00, 0x83, 0x01, 0x58, byte(len(a.Bytes())))
+	expected = append(expected, a.Bytes()...)
+	expected = append(expected, 0x19, 0x01, 0x2c)
+	require.Equal(t, expected, data)
+
+	var decoded f3.ECChain
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	require.Equal(t, chain, decoded)
+}
+
+func TestPowerTableRoundTrip(t *testing.T) {
+	table := f3.NewPowerTable()
+	large, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	require.True(t, ok)
+	table.Add(3, large, []byte("key3"))
+	table.Add(1, big.NewInt(5), []byte("key1"))
+	data, err := table.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded f3.PowerTable
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	require.Equal(t, table, decoded)
+
+	// Duplicate entries are rejected.
+	dup := f3.PowerTable{Entries: []f3.PowerEntry{{ID: 1, Power: big.NewInt(1)}, {ID: 1, Power: big.NewInt(2)}}}
+	data, err = dup.MarshalBinary()
+	require.NoError(t, err)
+	require.ErrorIs(t, decoded.UnmarshalBinary(data), f3.ErrInvalidEncoding)
+
+	// Power with a leading zero byte is rejected.
+	nonMinimal := []byte{0x82, 0x01, 0x81, 0x83, 0x01, 0x42, 0x00, 0x01, 0x40}
+	require.ErrorIs(t, decoded.UnmarshalBinary(nonMinimal), f3.ErrInvalidEncoding)
+	minimal := []byte{0x82, 0x01, 0x81, 0x83, 0x01, 0x41, 0x01, 0x40}
+	require.NoError(t, decoded.UnmarshalBinary(minimal))
+}
+
+func TestTicketRoundTrip(t *testing.T) {
+	ticket := f3.Ticket("ticket")
+	data, err := ticket.MarshalBinary()
+	require.NoError(t, err)
+	var decoded f3.Ticket
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	require.Equal(t, ticket, decoded)
+}
+
+func TestStrictDecoding(t *testing.T) {
+	ts := f3.NewTipSet(1, f3.CIDOf([]byte("a")), 2)
+	valid, err := ts.MarshalBinary()
+	require.NoError(t, err)
+
+	for name, data := range map[string][]byte{
+		"empty":           {},
+		"truncated":       valid[:len(valid)-1],
+		"trailing":        append(append([]byte{}, valid...), 0x00),
+		"unknown version": {0x82, 0x02, 0x83, 0x01, 0x40, 0x02},
+		"non-minimal":     {0x82, 0x01, 0x83, 0x18, 0x01, 0x40, 0x02},
+		"indefinite":      {0x82, 0x01, 0x9f, 0x01, 0x40, 0x02, 0xff},
+		"wrong type":      {0x82, 0x01, 0x83, 0x01, 0x60, 0x02},
+		"wrong length":    {0x82, 0x01, 0x82, 0x01, 0x40},
+		"invalid CID":     {0x82, 0x01, 0x83, 0x01, 0x41, 'a', 0x02},
+	} {
+		var decoded f3.TipSet
+		require.ErrorIs(t, decoded.UnmarshalBinary(data), f3.ErrInvalidEncoding, name)
+	}
+
+	// Over-long strings are neither encoded nor decoded.
+	long := make([]byte, f3.MaxEncodedBytesLength+1)
+	_, err = f3.Ticket(long).MarshalBinary()
+	require.Error(t, err)
+	data := append([]byte{0x82, 0x01, 0x59, byte(len(long) >> 8), byte(len(long))}, long...)
+	var decoded f3.Ticket
+	require.ErrorIs(t, decoded.UnmarshalBinary(data), f3.ErrInvalidEncoding)
+}
+
+func TestLargestSignerIndexRoundTrip(t *testing.T) {
+	chain := f3.NewChain(f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1))
+	signers := f3.NewBitfield(0, f3.MaxEncodedPowerTableEntries-1)
+	cert := &f3.FinalityCertificate{Instance: 3, Value: chain, Round: 1, Signers: signers,
+		SignersPower: big.NewInt(7), Signature: []byte("agg")}
+	entry := &f3.JournalEntry{Kind: f3.JournalDecide, Instance: 3, Certificate: cert}
+	msg := &f3.GMessage{Sender: 1, Instance: 3, Round: 1, Step: f3.COMMIT, Value: chain, Signature: []byte("sig"),
+		Justification: &f3.Justification{Round: 1, Step: f3.PREPARE, Value: chain, Signers: signers, Signature: []byte("agg")}}
+
+	data, err := cert.MarshalBinary()
+	require.NoError(t, err)
+	var decodedCert f3.FinalityCertificate
+	require.NoError(t, decodedCert.UnmarshalBinary(data))
+	require.Equal(t, *cert, decodedCert)
+
+	data, err = entry.MarshalBinary()
+	require.NoError(t, err)
+	var decodedEntry f3.JournalEntry
+	require.NoError(t, decodedEntry.UnmarshalBinary(data))
+	require.Equal(t, *entry, decodedEntry)
+
+	data, err = msg.MarshalBinary()
+	require.NoError(t, err)
+	var decodedMsg f3.GMessage
+	require.NoError(t, decodedMsg.UnmarshalBinary(data))
+	require.Equal(t, *msg, decodedMsg)
+
+	// A signer beyond the largest power table is not encoded.
+	cert.Signers = f3.NewBitfield(f3.MaxEncodedPowerTableEntries)
+	_, err = cert.MarshalBinary()
+	require.Error(t, err)
+}
+
+func TestVRFPayload(t *testing.T) {
+	// "VRF:" followed by the CBOR array [h'0102', 3, 4].
+	require.Equal(t, []byte("VRF:\x83\x42\x01\x02\x03\x04"), f3.VRFPayload([]byte{1, 2}, 3, 4))
+	require.NotEqual(t, f3.VRFPayload([]byte{1}, 0, 0), f3.VRFPayload([]byte{1}, 0, 1))
+}
+
+func TestJournalEntryRoundTrip(t *testing.T) {
+	base := f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1)
+	chain := f3.NewChain(base, f3.NewTipSet(101, f3.CIDOf([]byte("a")), 2))
+	for _, entry := range []*f3.JournalEntry{
+		{Kind: f3.JournalStart, Instance: 3, Input: chain},
+		{Kind: f3.JournalSend, Instance: 3, Proposal: chain, Value: chain,
+			Message: &f3.GMessage{Sender: 1, Instance: 3, Round: 0, Step: f3.QUALITY, Value: chain,
+				Signature: []byte("sig")}},
+		{Kind: f3.JournalDecide, Instance: 3, Certificate: &f3.FinalityCertificate{Instance: 3, Value: chain,
+			Round: 1, Signers: f3.NewBitfield(0, 2), SignersPower: big.NewInt(7), Signature: []byte("agg")}},
+	} {
+		data, err := entry.MarshalBinary()
+		require.NoError(t, err)
+		var decoded f3.JournalEntry
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		require.Equal(t, *entry, decoded)
+	}
+}
@@ -0,0 +1,58 @@
+package f3
+
+import (
+	"fmt"
+)
+
+// Evidence that a participant sent two conflicting messages for the same instance, round and step,
+// in a step which allows only one value per sender.
+// Both messages carry the sender's signature, so the evidence can be checked by a third party.
+type EquivocationEvidence struct {
+	// The message first received from the sender.
+	First GMessage
+	// A subsequent message with a different value.
+	Second GMessage
+}
+
+// Returns the equivocating participant.
+func (e *EquivocationEvidence) Sender() ActorID {
+	return e.First.Sender
+}
+
+// Checks that the evidence comprises two validly signed messages from the same sender,
+// for the same instance, round and step, with different values.
+func (e *EquivocationEvidence) Verify(powerTable PowerTable, verifier Verifier) error {
+	a, b := &e.First, &e.Second
+	if a.Sender != b.Sender {
+		return fmt.Errorf("messages from different senders %d and %d", a.Sender, b.Sender)
+	}
+	if a.Instance != b.Instance || a.Round != b.Round || a.Step != b.Step {
+		return fmt.Errorf("messages for different steps %s and %s", a, b)
+	}
+	if !allowsSingleValue(a.Step) {
+		return fmt.Errorf("step %s allows multiple values", a.Step)
+	}
+	if a.Value.Eq(b.Value) {
+		return fmt.Errorf("messages have the same value %s", &a.Value)
+	}
+	_, pubKey := powerTable.Get(a.Sender)
+	if pubKey == nil {
+		return fmt.Errorf("sender %d not in power table", a.Sender)
+	}
+	for _, msg := range []*GMessage{a, b} {
+		if !verifier.Verify(pubKey, msg.SignaturePayload(), msg.Signature) {
+			return fmt.Errorf("invalid signature on %s", msg)
+		}
+	}
+	return nil
+}
+
+func (e *EquivocationEvidence) String() string {
+	return fmt.Sprintf("EQUIVOCATION(P%d: %s, %s)", e.Sender(), e.First, e.Second)
+}
+
+// Checks whether a step allows each sender only a single value per round.
+// QUALITY messages propose a chain, each prefix of which is received independently.
+func allowsSingleValue(step string) bool {
+	return step == CONVERGE || step == PREPARE || step == COMMIT
+}
@@ -2,6 +2,8 @@ package f3
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"sort"
 )
 
@@ -10,6 +12,73 @@ type GraniteConfig struct {
 	Delta float64
 	// Change to delta in each round after the first.
 	DeltaRate float64
+	// Policy determining phase timeouts: TimeoutLinear (the default), TimeoutExponential, or TimeoutAdaptive.
+	Timeout TimeoutKind
+	// Factor by which the exponential timeout increases with each round.
+	DeltaBackoff float64
+	// Minimum first round timeout learned by the adaptive timeout.
+	DeltaMin float64
+	// Maximum timeout for any phase. Zero means unbounded.
+	DeltaMax float64
+	// Number of epochs before an instance's base from which to take the power table.
+	PowerTableLookback int
+	// Delay after a phase timeout without progress before re-sending this participant's messages.
+	// Zero disables rebroadcast.
+	RebroadcastDelay float64
+	// Factor by which the delay increases after each rebroadcast without progress.
+	// Values less than one are treated as one.
+	RebroadcastBackoff float64
+	// Maximum delay between rebroadcasts. Zero means unbounded.
+	RebroadcastMaxDelay float64
+	// Number of instances after the current one for which messages are queued.
+	// Zero selects DefaultMaxFutureInstances, and a negative value means unbounded.
+	MaxFutureInstances int
+	// Number of rounds after an instance's current round for which messages are accepted.
+	// Zero selects DefaultMaxFutureRounds, and a negative value means unbounded.
+	MaxFutureRounds int
+	// Maximum number of messages from any one sender held in each queue.
+	// The bound protects honest senders only if senders are authenticated before messages are queued:
+	// messages for future instances are queued before their signatures can be verified,
+	// so a sender forging others' messages can fill their share of the queue.
+	// Zero selects DefaultMaxQueuedPerSender, and a negative value means unbounded.
+	MaxQueuedPerSender int
+	// Maximum number of messages held in each queue.
+	// Zero selects DefaultMaxQueued, and a negative value means unbounded.
+	MaxQueued int
+	// Maximum number of tipsets, including the base, in a chain value.
+	// Longer input chains are truncated, and messages with longer values are rejected.
+	// Zero means unbounded. Otherwise this must be at least two, so that a truncated input extends its base.
+	MaxChainLength int
+	// Policy for making room for a message in a full queue: EvictFurthest (the default) or RejectNewest.
+	QueueEviction EvictionPolicy
+	// Maximum number of canonical chains received after an instance begins which it retains as acceptable
+	// to vote for, in addition to its input. Older chains are discarded as more recent ones are received.
+	// Zero selects DefaultMaxAcceptableChains, and a negative value means unbounded.
+	MaxAcceptableChains int
+}
+
+// Defaults for the bounds on queued messages and retained chains, selected by zero values in GraniteConfig.
+const (
+	DefaultMaxFutureInstances  = 5
+	DefaultMaxFutureRounds     = 10
+	DefaultMaxQueuedPerSender  = 128
+	DefaultMaxQueued           = 1 << 16
+	DefaultMaxAcceptableChains = 8
+)
+
+// Returns a copy of the configuration with defaults in place of zero values.
+func (c GraniteConfig) withDefaults() GraniteConfig {
+	setDefault := func(value *int, def int) {
+		if *value == 0 {
+			*value = def
+		}
+	}
+	setDefault(&c.MaxFutureInstances, DefaultMaxFutureInstances)
+	setDefault(&c.MaxFutureRounds, DefaultMaxFutureRounds)
+	setDefault(&c.MaxQueuedPerSender, DefaultMaxQueuedPerSender)
+	setDefault(&c.MaxQueued, DefaultMaxQueued)
+	setDefault(&c.MaxAcceptableChains, DefaultMaxAcceptableChains)
+	return c
 }
 
 type VRFer interface {
@@ -30,6 +99,12 @@ type GMessage struct {
 	Step     string
 	Ticket   Ticket
 	Value    ECChain
+	// Signature by the sender's key over the message's signature payload.
+	Signature []byte
+	// Proof that the value is allowed, for a step requiring justification by prior messages.
+	// Nil if the sender could not provide one, in which case the receiver must observe the prior messages itself.
+	// The justification is not covered by the signature; it is independently verifiable.
+	Justification *Justification
 }
 
 func (m GMessage) String() string {
@@ -37,15 +112,32 @@ func (m GMessage) String() string {
 	return fmt.Sprintf("%s{%d}(%d %s)", m.Step, m.Instance, m.Round, &m.Value)
 }
 
+// Returns the payload that the sender signs.
+// The payload digests the instance, round, step and value, but not the sender or ticket,
+// so that all senders of the same value produce signatures over the same payload.
+func (m *GMessage) SignaturePayload() []byte {
+	return SignaturePayload(m.Instance, m.Round, m.Step, m.Value)
+}
+
 // A single Granite consensus instance.
 type instance struct {
-	config        GraniteConfig
-	ntwk          Network
+	config GraniteConfig
+	// The network, guarded against sending messages which conflict with those already sent.
+	ntwk          *sendGuard
 	vrf           VRFer
+	signer        SignerVerifier
+	timeouts      TimeoutPolicy
 	participantID ActorID
 	instanceID    int
 	// The EC chain input to this instance.
 	input ECChain
+	// Chains this instance may vote for: the input and canonical chains received since, from the same base.
+	// Ordered oldest first. The input is always retained, and later chains are bounded by the config.
+	acceptable []ECChain
+	// The local EC chain store, whose heaviest chain is also acceptable. May be nil.
+	chainStore ChainStore
+	// Journal to which each message is written before it is sent. May be nil.
+	journal Journal
 	// The power table for the base chain, used for power in this instance.
 	powerTable PowerTable
 	// The beacon value from the base chain, used for tickets in this instance.
@@ -58,32 +150,78 @@ type instance struct {
 	// For QUALITY, PREPARE, and COMMIT, this is the latest time (the phase can end sooner).
 	// For CONVERGE, this is the exact time (the timeout solely defines the phase end).
 	phaseTimeout float64
+	// Time at which the current phase began.
+	phaseStart float64
+	// The alarm set for the current phase's timeout or rebroadcast, if it has not yet fired.
+	alarm *Alarm
+	// Number of rebroadcasts since the current phase began.
+	rebroadcasts int
 	// This instance's proposal for the current round.
 	// This is set after the QUALITY phase, and changes only at the end of a full round.
 	proposal ECChain
 	// The value to be transmitted at the next phase.
 	// This value may change away from the proposal between phases.
 	value ECChain
+	// Aggregate of the COMMIT signatures for the decided value, set upon decision.
+	decision *FinalityCertificate
 	// Queue of messages to be synchronously processed before returning from top-level call.
 	inbox []*GMessage
 	// Messages received earlier but not yet justified.
 	pending *pendingQueue
+	// Counters of messages dropped to bound memory use.
+	stats *QueueStats
 	// Quality phase state (only for round 0)
 	quality *quorumState
 	// State for each round of phases.
 	// State from prior rounds must be maintained to provide justification for values in subsequent rounds.
 	rounds map[int]*roundState
+	// The first message received from each sender in each round and step allowing only a single value.
+	firstMessages map[senderStep]*GMessage
+	// Senders already reported as equivocating in each round and step.
+	equivocators map[senderStep]bool
+	// Callback invoked with evidence of each equivocation.
+	onEquivocation func(evidence *EquivocationEvidence)
+	// Callback invoked if a message fails to journal, after which the instance sends nothing further.
+	onFailure func(err error)
+	// The error with which a message failed to journal, if any.
+	failure error
+	// Messages broadcast by this instance, by round, retained for rebroadcast.
+	sent map[int][]*GMessage
+	// Valid justifications received for quorums not necessarily observed directly.
+	// These justify both subsequent received messages and this instance's own messages.
+	justifications map[quorumKey]*Justification
+}
+
+// Identifies a quorum of messages for a value in some round and step.
+type quorumKey struct {
+	round int
+	step  string
+	head  CID
+}
+
+// Identifies a sender's message in some round and step.
+type senderStep struct {
+	sender ActorID
+	round  int
+	step   string
 }
 
 func newInstance(
 	config GraniteConfig,
-	ntwk Network,
+	ntwk *sendGuard,
 	vrf VRFer,
+	signer SignerVerifier,
+	timeouts TimeoutPolicy,
 	participantID ActorID,
 	instanceID int,
 	input ECChain,
 	powerTable PowerTable,
-	beacon []byte) *instance {
+	beacon []byte,
+	chainStore ChainStore,
+	journal Journal,
+	stats *QueueStats,
+	onEquivocation func(evidence *EquivocationEvidence),
+	onFailure func(err error)) *instance {
 	if input.IsZero() {
 		panic("input is empty")
 	}
@@ -91,20 +229,32 @@ func newInstance(
 		config:        config,
 		ntwk:          ntwk,
 		vrf:           vrf,
+		signer:        signer,
+		timeouts:      timeouts,
 		participantID: participantID,
 		instanceID:    instanceID,
 		input:         input,
+		acceptable:    []ECChain{input},
 		powerTable:    powerTable,
 		beacon:        beacon,
+		chainStore:    chainStore,
+		journal:       journal,
 		round:         0,
 		phase:         "",
 		proposal:      input,
 		value:         ECChain{},
-		pending:       newPendingQueue(),
-		quality:       newQuorumState(powerTable),
+		pending:       newPendingQueue(newQueueBounds(config, stats)),
+		stats:         stats,
+		quality:       newQuorumState(powerTable, signer),
 		rounds: map[int]*roundState{
-			0: newRoundState(powerTable),
+			0: newRoundState(powerTable, signer),
 		},
+		firstMessages:  map[senderStep]*GMessage{},
+		equivocators:   map[senderStep]bool{},
+		onEquivocation: onEquivocation,
+		onFailure:      onFailure,
+		sent:           map[int][]*GMessage{},
+		justifications: map[quorumKey]*Justification{},
 	}
 }
 
@@ -114,11 +264,11 @@ type roundState struct {
 	committed *quorumState
 }
 
-func newRoundState(powerTable PowerTable) *roundState {
+func newRoundState(powerTable PowerTable, aggregator Aggregator) *roundState {
 	return &roundState{
 		converged: newConvergeState(),
-		prepared:  newQuorumState(powerTable),
-		committed: newQuorumState(powerTable),
+		prepared:  newQuorumState(powerTable, aggregator),
+		committed: newQuorumState(powerTable, aggregator),
 	}
 }
 
@@ -127,6 +277,32 @@ func (i *instance) Start() {
 	i.drainInbox()
 }
 
+// Resumes the instance after a restart from the journaled messages it sent, in order.
+// The instance takes the state in which it sent the last message, receives its own messages again,
+// and re-sends its latest messages, so that it never sends a message conflicting with one sent before.
+func (i *instance) Resume(sent []*JournalEntry) {
+	if len(sent) == 0 {
+		i.Start()
+		return
+	}
+	for _, entry := range sent {
+		msg := entry.Message
+		i.round, i.phase = msg.Round, msg.Step
+		i.proposal, i.value = entry.Proposal, entry.Value
+		i.sent[msg.Round] = append(i.sent[msg.Round], msg)
+		i.enqueueInbox(msg)
+		if err := i.ntwk.Restore(msg); err != nil {
+			i.log("‼️ journal conflicts with itself: %s", err)
+		}
+	}
+	i.log("resuming after restart")
+	i.phaseTimeout = i.alarmAfterSynchrony()
+	for _, msg := range i.latestSent() {
+		i.ntwk.Broadcast(msg)
+	}
+	i.drainInbox()
+}
+
 func (i *instance) Receive(msg *GMessage) {
 	if i.decided() {
 		panic("received message after decision")
@@ -140,13 +316,26 @@ func (i *instance) Receive(msg *GMessage) {
 	i.drainInbox()
 }
 
-func (i *instance) ReceiveAlarm(_ string) {
+// Receives an alarm set by this instance.
+// An alarm for any round and phase other than the current one is ignored.
+func (i *instance) ReceiveAlarm(alarm Alarm) {
+	if alarm.Instance != i.instanceID || alarm.Round != i.round || alarm.Phase != i.phase {
+		i.log("ignoring stale %s", alarm)
+		return
+	}
+	i.alarm = nil
+	round, phase := i.round, i.phase
 	i.tryCompletePhase()
 
 	// A phase may have been successfully completed.
 	// Re-process any queued messages for the next phase.
 	i.tryPendingMessages()
 	i.drainInbox()
+
+	// If the phase cannot complete after its timeout, some messages may have been lost.
+	if i.round == round && i.phase == phase && !i.decided() {
+		i.rebroadcast()
+	}
 }
 
 func (i *instance) Describe() string {
@@ -183,11 +372,23 @@ func (i *instance) tryPendingMessages() {
 
 // Processes a single message.
 func (i *instance) receiveOne(msg *GMessage) {
+	// Drop any message too far ahead of the current round, before it can occupy any state
+	// or cost a signature verification.
+	if i.config.MaxFutureRounds > 0 && msg.Round > i.round+i.config.MaxFutureRounds {
+		i.log("dropping %s beyond round window", msg)
+		i.stats.DroppedFuture += 1
+		return
+	}
 	// Drop any messages that can never be valid.
 	if !i.isValid(msg) {
 		i.log("dropping invalid %s", msg)
 		return
 	}
+	// Drop any message conflicting with one already received from the same sender.
+	if i.isEquivocation(msg) {
+		i.log("dropping equivocating %s from %d", msg, msg.Sender)
+		return
+	}
 
 	// Hold as pending any message with a value not yet justified by the prior phase.
 	if !i.isJustified(msg) {
@@ -202,14 +403,16 @@ func (i *instance) receiveOne(msg *GMessage) {
 		// Receive each prefix of the proposal independently.
 		for j := range msg.Value.Suffix() {
 			prefix := msg.Value.Prefix(j + 1)
-			i.quality.Receive(msg.Sender, prefix)
+			// The signature covers only the whole value, so is not retained for prefixes.
+			i.quality.Receive(msg.Sender, prefix, nil)
 		}
 	case CONVERGE:
-		round.converged.Receive(msg.Value, msg.Ticket)
+		power, _ := i.powerTable.Get(msg.Sender)
+		round.converged.Receive(msg.Sender, msg.Value, msg.Ticket, ScalePower(power, i.powerTable.Total, TicketPowerScale))
 	case PREPARE:
-		round.prepared.Receive(msg.Sender, msg.Value)
+		round.prepared.Receive(msg.Sender, msg.Value, msg.Signature)
 	case COMMIT:
-		round.committed.Receive(msg.Sender, msg.Value)
+		round.committed.Receive(msg.Sender, msg.Value, msg.Signature)
 	default:
 		i.log("unexpected message %v", msg)
 	}
@@ -246,13 +449,94 @@ func (i *instance) tryCompletePhase() {
 // Checks whether a message is valid.
 // An invalid message can never become valid, so may be dropped.
 func (i *instance) isValid(msg *GMessage) bool {
-	if !(msg.Value.IsZero() || msg.Value.HasBase(i.input.Base())) {
-		i.log("unexpected base %s", &msg.Value)
+	if err := i.validate(msg); err != nil {
+		i.log("invalid %s: %s", msg, err)
 		return false
 	}
+	return true
+}
+
+// Checks that a message is well formed, and is validly signed by a participant in this instance.
+// Returns an error wrapping one of the validation errors if not.
+func (i *instance) validate(msg *GMessage) error {
+	if msg.Step == DECIDE {
+		return fmt.Errorf("%w: DECIDE received by instance", ErrUnknownStep)
+	}
+	if err := msg.Validate(i.config.MaxChainLength); err != nil {
+		return err
+	}
+	_, pubKey := i.powerTable.Get(msg.Sender)
+	if pubKey == nil {
+		return fmt.Errorf("%w: %d not in power table", ErrUnknownSender, msg.Sender)
+	}
+	if !i.signer.Verify(pubKey, msg.SignaturePayload(), msg.Signature) {
+		return ErrInvalidSignature
+	}
+	if !(msg.Value.IsZero() || msg.Value.HasBase(i.input.Base())) {
+		return fmt.Errorf("%w: unexpected base %s", ErrInvalidValue, msg.Value.Base())
+	}
 	if msg.Step == CONVERGE {
-		if !i.vrf.VerifyTicket(i.beacon, i.instanceID, msg.Round, msg.Sender, msg.Ticket) {
-			return false
+		if !i.vrf.VerifyTicket(i.beacon, i.instanceID, msg.Round, pubKey, msg.Ticket) {
+			return fmt.Errorf("%w: ticket does not verify", ErrInvalidTicket)
+		}
+	}
+	if msg.Justification != nil {
+		return i.verifyJustification(msg)
+	}
+	return nil
+}
+
+// Checks that a message's justification is for a quorum which would justify the message, and verifies it
+// unless the same quorum is already proven. A valid justification is retained to justify subsequent messages,
+// so each justification is verified at most once, and a message with an invalid one is dropped.
+func (i *instance) verifyJustification(msg *GMessage) error {
+	j := msg.Justification
+	var justifies bool
+	switch msg.Step {
+	case CONVERGE:
+		// A strong quorum of PREPARE for the same value, or of COMMIT for bottom, in the previous round.
+		justifies = j.Round == msg.Round-1 &&
+			((j.Step == PREPARE && j.Value.Eq(msg.Value)) || (j.Step == COMMIT && j.Value.IsZero()))
+	case COMMIT:
+		// A strong quorum of PREPARE for the same value in the same round.
+		justifies = j.Round == msg.Round && j.Step == PREPARE && j.Value.Eq(msg.Value)
+	}
+	if !justifies {
+		return fmt.Errorf("%w: %s does not justify %s", ErrInvalidJustification, j, msg.Step)
+	}
+	key := quorumKey{j.Round, j.Step, j.Value.HeadCIDOrZero()}
+	if _, ok := i.justifications[key]; ok {
+		return nil
+	}
+	if err := j.Verify(i.instanceID, i.powerTable, i.signer); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidJustification, j, err)
+	}
+	i.justifications[key] = j
+	return nil
+}
+
+// Checks whether a message conflicts with the first message from the same sender in a step
+// which allows only a single value, reporting evidence of the first such conflict.
+// Receiving the same message again is not equivocation.
+func (i *instance) isEquivocation(msg *GMessage) bool {
+	if !allowsSingleValue(msg.Step) {
+		return false
+	}
+	key := senderStep{msg.Sender, msg.Round, msg.Step}
+	first, ok := i.firstMessages[key]
+	if !ok {
+		i.firstMessages[key] = msg
+		return false
+	}
+	if first.Value.Eq(msg.Value) {
+		return false
+	}
+	if !i.equivocators[key] {
+		i.equivocators[key] = true
+		evidence := &EquivocationEvidence{First: *first, Second: *msg}
+		i.log("⚠️ %s", evidence)
+		if i.onEquivocation != nil {
+			i.onEquivocation(evidence)
 		}
 	}
 	return true
@@ -272,8 +556,13 @@ func (i *instance) isJustified(msg *GMessage) bool {
 			return false
 		}
 		prevRound := i.roundState(msg.Round - 1)
-		return prevRound.prepared.HasQuorumAgreement(msg.Value.Head().CID) ||
-			prevRound.committed.HasQuorumAgreement("")
+		if prevRound.prepared.HasQuorumAgreement(msg.Value.Head().CID) ||
+			prevRound.committed.HasQuorumAgreement(ZeroCID) {
+			return true
+		}
+		// Without having observed the quorum, the message must carry its own proof.
+		return i.hasJustification(msg.Round-1, PREPARE, msg.Value) ||
+			i.hasJustification(msg.Round-1, COMMIT, ECChain{})
 	} else if msg.Step == PREPARE {
 		// PREPARE needs no justification by prior messages.
 		return true // i.quality.AllowsValue(msg.Value)
@@ -281,17 +570,54 @@ func (i *instance) isJustified(msg *GMessage) bool {
 		// COMMIT is justified by strong quorum of PREPARE from the same round with the same value.
 		// COMMIT for bottom is always justified.
 		round := i.roundState(msg.Round)
-		return msg.Value.IsZero() || round.prepared.HasQuorumAgreement(msg.Value.HeadCIDOrZero())
+		return msg.Value.IsZero() || round.prepared.HasQuorumAgreement(msg.Value.HeadCIDOrZero()) ||
+			i.hasJustification(msg.Round, PREPARE, msg.Value)
 	}
 	return false
 }
 
+// Checks whether a strong quorum of messages with some round, step and value is proven by a valid justification
+// received previously, including one carried by the message being justified, which was verified with it.
+func (i *instance) hasJustification(round int, step string, value ECChain) bool {
+	_, ok := i.justifications[quorumKey{round, step, value.HeadCIDOrZero()}]
+	return ok
+}
+
+// Builds a justification from the strong quorum of messages received for a value in some round and step,
+// or returns one previously received for it.
+// Returns nil if there is no such quorum, or the signatures cannot be aggregated.
+func (i *instance) justify(round int, step string, value ECChain) *Justification {
+	if round < 0 {
+		return nil
+	}
+	var quorum *quorumState
+	switch step {
+	case PREPARE:
+		quorum = i.roundState(round).prepared
+	case COMMIT:
+		quorum = i.roundState(round).committed
+	default:
+		return nil
+	}
+	cid := value.HeadCIDOrZero()
+	if !quorum.HasQuorumAgreement(cid) {
+		// The quorum may be known only from a justification received from another participant.
+		return i.justifications[quorumKey{round, step, cid}]
+	}
+	signers, _, sig, err := quorum.Aggregate(cid)
+	if err != nil {
+		i.log("failed to aggregate %s signatures: %s", step, err)
+		return nil
+	}
+	return &Justification{Round: round, Step: step, Value: value, Signers: signers, Signature: sig}
+}
+
 // Sends this node's QUALITY message and begins the QUALITY phase.
 func (i *instance) beginQuality() {
 	// Broadcast input value and wait up to Δ to receive from others.
 	i.phase = QUALITY
-	i.phaseTimeout = i.alarmAfterSynchrony(QUALITY)
-	i.broadcast(QUALITY, i.input, nil)
+	i.phaseTimeout = i.alarmAfterSynchrony()
+	i.broadcast(QUALITY, i.input, nil, nil)
 }
 
 // Attempts to end the QUALITY phase and begin PREPARE based on current state.
@@ -307,6 +633,7 @@ func (i *instance) tryQuality() {
 
 	if foundQuorum {
 		// Keep current proposal.
+		i.observeQuorum()
 	} else if timeoutExpired {
 		strongQuora := i.quality.ListQuorumAgreedValues()
 		i.proposal = findFirstPrefixOf(strongQuora, i.proposal)
@@ -321,9 +648,15 @@ func (i *instance) tryQuality() {
 
 func (i *instance) beginConverge() {
 	i.phase = CONVERGE
-	ticket := i.vrf.MakeTicket(i.beacon, i.instanceID, i.round, i.participantID)
-	i.phaseTimeout = i.alarmAfterSynchrony(CONVERGE)
-	i.broadcast(CONVERGE, i.proposal, ticket)
+	_, pubKey := i.powerTable.Get(i.participantID)
+	ticket := i.vrf.MakeTicket(i.beacon, i.instanceID, i.round, pubKey)
+	i.phaseTimeout = i.alarmAfterSynchrony()
+	// Justify the proposal by the previous round's strong quorum of PREPARE for it, or of COMMIT for bottom.
+	justification := i.justify(i.round-1, PREPARE, i.proposal)
+	if justification == nil {
+		justification = i.justify(i.round-1, COMMIT, ECChain{})
+	}
+	i.broadcast(CONVERGE, i.proposal, ticket, justification)
 }
 
 // Attempts to end the CONVERGE phase and begin PREPARE based on current state.
@@ -358,8 +691,8 @@ func (i *instance) tryConverge() {
 func (i *instance) beginPrepare() {
 	// Broadcast preparation of value and wait for everyone to respond.
 	i.phase = PREPARE
-	i.phaseTimeout = i.alarmAfterSynchrony(PREPARE)
-	i.broadcast(PREPARE, i.value, nil)
+	i.phaseTimeout = i.alarmAfterSynchrony()
+	i.broadcast(PREPARE, i.value, nil, nil)
 }
 
 // Attempts to end the PREPARE phase and begin COMMIT based on current state.
@@ -376,6 +709,7 @@ func (i *instance) tryPrepare() {
 
 	if foundQuorum {
 		i.value = i.proposal
+		i.observeQuorum()
 	} else if timeoutExpired {
 		i.value = ECChain{}
 	}
@@ -387,8 +721,13 @@ func (i *instance) tryPrepare() {
 
 func (i *instance) beginCommit() {
 	i.phase = COMMIT
-	i.phaseTimeout = i.alarmAfterSynchrony(PREPARE)
-	i.broadcast(COMMIT, i.value, nil)
+	i.phaseTimeout = i.alarmAfterSynchrony()
+	// Justify a non-bottom value by this round's strong quorum of PREPARE for it.
+	var justification *Justification
+	if !i.value.IsZero() {
+		justification = i.justify(i.round, PREPARE, i.value)
+	}
+	i.broadcast(COMMIT, i.value, nil, justification)
 }
 
 func (i *instance) tryCommit(round int) {
@@ -402,6 +741,9 @@ func (i *instance) tryCommit(round int) {
 	if len(foundQuorum) > 0 && !foundQuorum[0].IsZero() {
 		// A participant may be forced to decide a value that's not its preferred chain.
 		// The participant isn't influencing that decision against their interest, just accepting it.
+		if i.round == round && i.phase == COMMIT {
+			i.observeQuorum()
+		}
 		i.decide(foundQuorum[0], round)
 	} else if i.round == round && i.phase == COMMIT && timeoutExpired && committed.ReceivedFromQuorum() {
 		// Adopt any non-empty value committed by another participant (there can only be one).
@@ -427,7 +769,7 @@ func (i *instance) tryCommit(round int) {
 func (i *instance) roundState(r int) *roundState {
 	round, ok := i.rounds[r]
 	if !ok {
-		round = newRoundState(i.powerTable)
+		round = newRoundState(i.powerTable, i.signer)
 		i.rounds[r] = round
 	}
 	return round
@@ -439,41 +781,182 @@ func (i *instance) beginNextRound() {
 	i.beginConverge()
 }
 
+// Receives a canonical chain learned after this instance began, which it may subsequently vote for.
+// A chain not beginning at this instance's base is ignored.
+func (i *instance) ReceiveAcceptable(chain ECChain) {
+	if !chain.HasBase(i.input.Base()) {
+		i.log("ignoring acceptable chain %s with unexpected base", &chain)
+		return
+	}
+	for _, c := range i.acceptable {
+		if c.Eq(chain) {
+			return
+		}
+	}
+	i.acceptable = append(i.acceptable, chain)
+	if max := i.config.MaxAcceptableChains; max > 0 && len(i.acceptable)-1 > max {
+		// Evict the oldest chain after the input which doesn't contain the current proposal, if any,
+		// so that the proposal remains acceptable.
+		evict := 1
+		for j := 1; j < len(i.acceptable); j++ {
+			if !i.acceptable[j].HasPrefix(i.proposal) {
+				evict = j
+				break
+			}
+		}
+		i.acceptable = append(i.acceptable[:evict:evict], i.acceptable[evict+1:]...)
+	}
+}
+
 // Returns whether a chain is acceptable as a proposal for this instance to vote for.
+// A chain is acceptable if it is a prefix of the input or of any subsequently received chain retained,
+// or if it is in the heaviest chain of the local chain store.
 // This is "EC Compatible" in the pseudocode.
 func (i *instance) isAcceptable(c ECChain) bool {
-	// TODO: expand to include subsequently notified chains.
-	return i.input.HasPrefix(c)
+	for _, acceptable := range i.acceptable {
+		if acceptable.HasPrefix(c) {
+			return true
+		}
+	}
+	if i.chainStore == nil {
+		return false
+	}
+	ok, err := isInHeaviestChain(i.chainStore, c)
+	if err != nil {
+		i.log("chain store: %s", err)
+	}
+	return ok
 }
 
+// Decides a value with a certificate capturing the strong quorum of COMMITs for it as a single aggregate signature.
+// If the signatures can't be aggregated, the decision is delayed until a subsequent COMMIT, rather than
+// producing a certificate which can't be verified.
 func (i *instance) decide(value ECChain, round int) {
-	i.log("✅ decided %s in round %d", &i.value, round)
+	signers, power, sig, err := i.roundState(round).committed.Aggregate(value.Head().CID)
+	if err != nil {
+		i.log("‼️ not deciding %s: failed to aggregate COMMIT signatures: %s", &value, err)
+		return
+	}
+	i.log("✅ decided %s in round %d", &value, round)
+	i.cancelAlarm()
 	i.phase = DECIDE
 	// Round is a parameter since a late COMMIT message can result in a decision for a round prior to the current one.
 	i.round = round
 	i.value = value
+	i.decision = &FinalityCertificate{
+		Instance:     i.instanceID,
+		Value:        value,
+		Round:        round,
+		Signers:      signers,
+		SignersPower: power,
+		Signature:    sig,
+	}
 }
 
 func (i *instance) decided() bool {
 	return i.phase == DECIDE
 }
 
-func (i *instance) broadcast(step string, value ECChain, ticket Ticket) *GMessage {
-	gmsg := &GMessage{i.participantID, i.instanceID, i.round, step, ticket, value}
+// Returns a certificate for the decided value, built from the deciding round's COMMIT quorum.
+// Nil if the instance has not decided.
+func (i *instance) certificate() *FinalityCertificate {
+	return i.decision
+}
+
+func (i *instance) broadcast(step string, value ECChain, ticket Ticket, justification *Justification) *GMessage {
+	gmsg := &GMessage{
+		Sender:        i.participantID,
+		Instance:      i.instanceID,
+		Round:         i.round,
+		Step:          step,
+		Ticket:        ticket,
+		Value:         value,
+		Justification: justification,
+	}
+	if i.failure != nil {
+		return gmsg
+	}
+	_, pubKey := i.powerTable.Get(i.participantID)
+	gmsg.Signature = i.signer.Sign(pubKey, gmsg.SignaturePayload())
+	// Refuse a conflicting message before journaling it, so that it is not restored after a restart either.
+	if err := i.ntwk.Check(gmsg); err != nil {
+		i.ntwk.refuse(err)
+		return gmsg
+	}
+	if i.journal != nil {
+		entry := &JournalEntry{Kind: JournalSend, Instance: i.instanceID, Proposal: i.proposal, Value: i.value,
+			Message: gmsg}
+		if err := i.journal.Append(entry); err != nil {
+			// Sending a message which isn't durable risks sending a conflicting one after a restart.
+			i.failure = fmt.Errorf("not sending %s which failed to journal: %w", gmsg, err)
+			if i.onFailure != nil {
+				i.onFailure(i.failure)
+			}
+			return gmsg
+		}
+	}
 	i.ntwk.Broadcast(gmsg)
 	i.enqueueInbox(gmsg)
+	i.sent[i.round] = append(i.sent[i.round], gmsg)
 	return gmsg
 }
 
-// Sets an alarm to be delivered after a synchrony delay.
-// The delay duration increases with each round.
+// Returns the messages this instance sent in the current and previous rounds, in order of sending.
+// The previous round's messages are included since its COMMIT phase remains open.
+func (i *instance) latestSent() []*GMessage {
+	var msgs []*GMessage
+	for _, round := range []int{i.round - 1, i.round} {
+		msgs = append(msgs, i.sent[round]...)
+	}
+	return msgs
+}
+
+// Re-sends this instance's latest messages, and sets an alarm for the current phase
+// to try again after a delay that increases with each attempt.
+func (i *instance) rebroadcast() {
+	if i.config.RebroadcastDelay <= 0 {
+		return
+	}
+	for _, msg := range i.latestSent() {
+		i.log("rebroadcast %s", msg)
+		i.ntwk.Broadcast(msg)
+	}
+	delay := i.config.RebroadcastDelay * math.Pow(math.Max(i.config.RebroadcastBackoff, 1), float64(i.rebroadcasts))
+	if i.config.RebroadcastMaxDelay > 0 {
+		delay = math.Min(delay, i.config.RebroadcastMaxDelay)
+	}
+	i.rebroadcasts += 1
+	i.alarm = &Alarm{Instance: i.instanceID, Round: i.round, Phase: i.phase}
+	i.ntwk.SetAlarm(i.participantID, *i.alarm, i.ntwk.Time()+delay)
+}
+
+// Sets an alarm for the current round and phase to be delivered after a synchrony delay,
+// cancelling any alarm pending for the previous phase.
+// The delay duration is determined by the timeout policy, and typically increases with each round.
 // Returns the absolute time at which the alarm will fire.
-func (i *instance) alarmAfterSynchrony(payload string) float64 {
-	timeout := i.ntwk.Time() + i.config.Delta + (float64(i.round) * i.config.DeltaRate)
-	i.ntwk.SetAlarm(i.participantID, payload, timeout)
+func (i *instance) alarmAfterSynchrony() float64 {
+	i.cancelAlarm()
+	i.rebroadcasts = 0
+	i.phaseStart = i.ntwk.Time()
+	timeout := i.phaseStart + i.timeouts.Timeout(i.instanceID, i.round, i.phase)
+	i.alarm = &Alarm{Instance: i.instanceID, Round: i.round, Phase: i.phase}
+	i.ntwk.SetAlarm(i.participantID, *i.alarm, timeout)
 	return timeout
 }
 
+// Reports the time taken to observe a strong quorum in the current phase to the timeout policy.
+func (i *instance) observeQuorum() {
+	i.timeouts.ObserveQuorum(i.instanceID, i.round, i.phase, i.ntwk.Time()-i.phaseStart)
+}
+
+// Cancels the pending alarm, if any.
+func (i *instance) cancelAlarm() {
+	if i.alarm != nil {
+		i.ntwk.CancelAlarm(i.participantID, *i.alarm)
+		i.alarm = nil
+	}
+}
+
 func (i *instance) log(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	i.ntwk.Log("P%d{%d}: %s (round %d, step %s, proposal %s, value %s)", i.participantID, i.instanceID, msg,
@@ -484,20 +967,35 @@ func (i *instance) log(format string, args ...interface{}) {
 
 // Holds a collection messages received but not yet justified.
 type pendingQueue struct {
+	bounds queueBounds
 	// Map by round and phase to list of messages.
 	rounds map[int]map[string][]*GMessage
+	// Number of queued messages from each sender.
+	senders map[ActorID]int
+	// Total number of queued messages.
+	size int
 }
 
-func newPendingQueue() *pendingQueue {
+func newPendingQueue(bounds queueBounds) *pendingQueue {
 	return &pendingQueue{
-		rounds: map[int]map[string][]*GMessage{},
+		bounds:  bounds,
+		rounds:  map[int]map[string][]*GMessage{},
+		senders: map[ActorID]int{},
 	}
 }
 
-// Queues a message for future re-validation.
+// Queues a message for future re-validation, dropping either it or a queued message if the queue is full.
 func (v *pendingQueue) Add(msg *GMessage) {
+	victim := v.bounds.victim(msg, v.senders[msg.Sender], v.size, v.all)
+	if victim == msg {
+		return
+	} else if victim != nil {
+		v.remove(victim)
+	}
 	rv := v.getRound(msg.Round)
 	rv[msg.Step] = append(rv[msg.Step], msg)
+	v.senders[msg.Sender] += 1
+	v.size += 1
 }
 
 // Dequeues all messages from some round and phase matching a predicate.
@@ -509,6 +1007,7 @@ func (v *pendingQueue) PopWhere(round int, phase string, pred func(msg *GMessage
 	for _, msg := range queue {
 		if pred(msg) {
 			found = append(found, msg)
+			v.removed(msg)
 		} else {
 			queue[n] = msg
 			n += 1
@@ -518,6 +1017,42 @@ func (v *pendingQueue) PopWhere(round int, phase string, pred func(msg *GMessage
 	return found
 }
 
+func (v *pendingQueue) Len() int {
+	return v.size
+}
+
+// Returns all queued messages.
+func (v *pendingQueue) all() []*GMessage {
+	var msgs []*GMessage
+	for _, rv := range v.rounds {
+		for _, queue := range rv {
+			msgs = append(msgs, queue...)
+		}
+	}
+	return msgs
+}
+
+// Removes a queued message.
+func (v *pendingQueue) remove(msg *GMessage) {
+	queue := v.rounds[msg.Round][msg.Step]
+	for j, q := range queue {
+		if q == msg {
+			v.rounds[msg.Round][msg.Step] = append(queue[:j:j], queue[j+1:]...)
+			v.removed(msg)
+			return
+		}
+	}
+}
+
+// Updates the counts for a message removed from the queue.
+func (v *pendingQueue) removed(msg *GMessage) {
+	v.size -= 1
+	v.senders[msg.Sender] -= 1
+	if v.senders[msg.Sender] == 0 {
+		delete(v.senders, msg.Sender)
+	}
+}
+
 func (v *pendingQueue) getRound(round int) map[string][]*GMessage {
 	var rv map[string][]*GMessage
 	rv, ok := v.rounds[round]
@@ -543,36 +1078,43 @@ type quorumState struct {
 	// The power supporting each chain so far.
 	chainPower map[CID]chainPower
 	// Total power of all distinct senders from which some chain has been received so far.
-	sendersTotalPower uint
+	sendersTotalPower *big.Int
 	// Table of senders' power.
 	powerTable PowerTable
+	// Aggregates senders' signatures.
+	aggregator Aggregator
 }
 
 // The set of chain heads from one sender, and that sender's power.
 type senderSent struct {
 	heads []CID
-	power uint
+	power *big.Int
 }
 
 // A chain value and the total power supporting it.
 type chainPower struct {
 	chain     ECChain
-	power     uint
+	power     *big.Int
 	hasQuorum bool
+	// Senders supporting the chain, in order of receipt.
+	senders []ActorID
+	// Senders' signatures for the chain, corresponding to senders.
+	signatures [][]byte
 }
 
 // Creates a new, empty quorum state.
-func newQuorumState(powerTable PowerTable) *quorumState {
+func newQuorumState(powerTable PowerTable, aggregator Aggregator) *quorumState {
 	return &quorumState{
 		received:          map[ActorID]senderSent{},
 		chainPower:        map[CID]chainPower{},
-		sendersTotalPower: 0,
+		sendersTotalPower: new(big.Int),
 		powerTable:        powerTable,
+		aggregator:        aggregator,
 	}
 }
 
-// Receives a new chain from a sender.
-func (q *quorumState) Receive(sender ActorID, value ECChain) {
+// Receives a new chain from a sender, with the sender's signature for it.
+func (q *quorumState) Receive(sender ActorID, value ECChain, signature []byte) {
 	head := value.HeadCIDOrZero()
 	fromSender, ok := q.received[sender]
 	if ok {
@@ -585,22 +1127,26 @@ func (q *quorumState) Receive(sender ActorID, value ECChain) {
 		fromSender.heads = append(fromSender.heads, head)
 	} else {
 		// Add sender's power to total the first time a value is received from them.
-		senderPower := q.powerTable.Entries[sender]
-		q.sendersTotalPower += senderPower
+		senderPower, _ := q.powerTable.Get(sender)
+		q.sendersTotalPower = new(big.Int).Add(q.sendersTotalPower, senderPower)
 		fromSender = senderSent{[]CID{head}, senderPower}
 	}
 	q.received[sender] = fromSender
 
+	senderPower, _ := q.powerTable.Get(sender)
 	candidate := chainPower{
-		chain:     value,
-		power:     q.powerTable.Entries[sender],
-		hasQuorum: false,
+		chain:      value,
+		power:      senderPower,
+		hasQuorum:  false,
+		senders:    []ActorID{sender},
+		signatures: [][]byte{signature},
 	}
 	if found, ok := q.chainPower[head]; ok {
-		candidate.power += found.power
+		candidate.power = new(big.Int).Add(found.power, senderPower)
+		candidate.senders = append(found.senders, sender)
+		candidate.signatures = append(found.signatures, signature)
 	}
-	threshold := q.powerTable.Total * 2 / 3
-	if candidate.power > threshold {
+	if IsStrongQuorum(candidate.power, q.powerTable.Total) {
 		candidate.hasQuorum = true
 	}
 	q.chainPower[head] = candidate
@@ -629,7 +1175,7 @@ func (q *quorumState) HasAgreement() bool {
 
 // Checks whether at least one message has been received from a strong quorum of senders.
 func (q *quorumState) ReceivedFromQuorum() bool {
-	return q.sendersTotalPower > q.powerTable.Total*2/3
+	return IsStrongQuorum(q.sendersTotalPower, q.powerTable.Total)
 }
 
 // Checks whether a chain (head) has reached quorum.
@@ -638,6 +1184,32 @@ func (q *quorumState) HasQuorumAgreement(cid CID) bool {
 	return ok && cp.hasQuorum
 }
 
+// Aggregates the signatures of the senders supporting a chain (head).
+// Returns a bitfield of the senders indexed by power table order, their total power, and the aggregate signature.
+func (q *quorumState) Aggregate(cid CID) (Bitfield, *big.Int, []byte, error) {
+	cp, ok := q.chainPower[cid]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("no senders for %s", cid)
+	}
+	// Aggregate in power table order, so a verifier can recover the keys from the bitfield.
+	indices := make([]int, len(cp.senders))
+	sigs := map[int][]byte{}
+	for j, sender := range cp.senders {
+		indices[j] = q.powerTable.Lookup[sender]
+		sigs[indices[j]] = cp.signatures[j]
+	}
+	sort.Ints(indices)
+	signers := NewBitfield(indices...)
+	pubKeys := make([]PubKey, len(indices))
+	orderedSigs := make([][]byte, len(indices))
+	for j, idx := range indices {
+		pubKeys[j] = q.powerTable.Entries[idx].PubKey
+		orderedSigs[j] = sigs[idx]
+	}
+	aggSig, err := q.aggregator.Aggregate(pubKeys, orderedSigs)
+	return signers, cp.power, aggSig, err
+}
+
 // Returns a list of the chains which have reached an agreeing quorum.
 // The order of returned values is not defined.
 func (q *quorumState) ListQuorumAgreedValues() []ECChain {
@@ -656,39 +1228,61 @@ func (q *quorumState) ListQuorumAgreedValues() []ECChain {
 type convergeState struct {
 	// Chains indexed by head CID
 	values map[CID]ECChain
-	// Tickets provided by proposers of each chain.
-	tickets map[CID][]Ticket
+	// The proposal and ticket of each sender.
+	senders map[ActorID]convergeValue
+}
+
+type convergeValue struct {
+	// Head CID of the proposed chain.
+	key    CID
+	ticket Ticket
+	// Rank of the ticket, weighted by the sender's power.
+	rank TicketRank
 }
 
 func newConvergeState() *convergeState {
 	return &convergeState{
 		values:  map[CID]ECChain{},
-		tickets: map[CID][]Ticket{},
+		senders: map[ActorID]convergeValue{},
 	}
 }
 
-// Receives a new CONVERGE value from a sender.
-func (c *convergeState) Receive(value ECChain, ticket Ticket) {
+// Receives a new CONVERGE value from a sender with some power, scaled by TicketPowerScale.
+// Only the first value from each sender is retained.
+func (c *convergeState) Receive(sender ActorID, value ECChain, ticket Ticket, scaledPower uint64) {
 	if value.IsZero() {
 		panic("bottom cannot be justified for CONVERGE")
 	}
+	if _, ok := c.senders[sender]; ok {
+		return
+	}
 	key := value.Head().CID
 	c.values[key] = value
-	c.tickets[key] = append(c.tickets[key], ticket)
+	c.senders[sender] = convergeValue{key: key, ticket: ticket, rank: ComputeTicketRank(ticket, scaledPower)}
 }
 
+// Returns the value proposed with the lowest-ranked ticket.
+// Equal ranks are broken by the lower ticket, then the lower sender ID, so the choice is deterministic.
 func (c *convergeState) findMinTicketProposal() ECChain {
-	var minTicket Ticket
-	var minValue ECChain
-	for cid, value := range c.values {
-		for _, ticket := range c.tickets[cid] {
-			if minTicket == nil || ticket.Compare(minTicket) < 0 {
-				minTicket = ticket
-				minValue = value
-			}
+	var minSender ActorID
+	var min *convergeValue
+	for sender, v := range c.senders {
+		v := v
+		rank := 0
+		if min != nil {
+			rank = v.rank.Compare(min.rank)
 		}
+		if min == nil || rank < 0 ||
+			(rank == 0 && (v.ticket.Compare(min.ticket) < 0 ||
+				(v.ticket.Compare(min.ticket) == 0 && sender < minSender))) {
+			minSender = sender
+			min = &v
+		}
+	}
+	if min == nil {
+		return ECChain{}
 	}
-	return minValue
+	return c.values[min.key]
 }
 
 ///// General helpers /////
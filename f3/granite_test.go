@@ -0,0 +1,471 @@
+package f3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func powerTableOf(powers ...*big.Int) PowerTable {
+	table := NewPowerTable()
+	for i, p := range powers {
+		table.Add(ActorID(i), p, nil)
+	}
+	return table
+}
+
+func TestIsStrongQuorum(t *testing.T) {
+	large, ok := new(big.Int).SetString("300000000000000000000000000000", 10)
+	require.True(t, ok)
+	twoThirds := new(big.Int).Div(new(big.Int).Mul(large, big.NewInt(2)), big.NewInt(3))
+
+	for _, test := range []struct {
+		power, total *big.Int
+		expected     bool
+	}{
+		{big.NewInt(0), big.NewInt(0), false},
+		{big.NewInt(2), big.NewInt(3), false},
+		{big.NewInt(3), big.NewInt(3), true},
+		{big.NewInt(3), big.NewInt(4), true},
+		{big.NewInt(6), big.NewInt(9), false},
+		{big.NewInt(7), big.NewInt(9), true},
+		// Exactly two thirds of a total beyond 64 bits is not a strong quorum, one more is.
+		{twoThirds, large, false},
+		{new(big.Int).Add(twoThirds, big.NewInt(1)), large, true},
+	} {
+		require.Equal(t, test.expected, IsStrongQuorum(test.power, test.total), "%s of %s", test.power, test.total)
+	}
+}
+
+func TestScalePower(t *testing.T) {
+	require.Equal(t, uint64(0), ScalePower(big.NewInt(1), big.NewInt(0), 0xffff))
+	require.Equal(t, uint64(0), ScalePower(big.NewInt(0), big.NewInt(10), 0xffff))
+	require.Equal(t, uint64(0xffff), ScalePower(big.NewInt(10), big.NewInt(10), 0xffff))
+	require.Equal(t, uint64(0xffff), ScalePower(big.NewInt(11), big.NewInt(10), 0xffff))
+	require.Equal(t, uint64(50), ScalePower(big.NewInt(1), big.NewInt(2), 100))
+	require.Equal(t, uint64(33), ScalePower(big.NewInt(1), big.NewInt(3), 100))
+
+	large := new(big.Int).Lsh(big.NewInt(1), 100)
+	quarter := new(big.Int).Rsh(large, 2)
+	require.Equal(t, uint64(25), ScalePower(quarter, large, 100))
+}
+
+func TestQuorumStateThreshold(t *testing.T) {
+	a := ECChain{NewTipSet(1, CIDOf([]byte("a")), 1)}
+	b := ECChain{NewTipSet(1, CIDOf([]byte("b")), 1)}
+
+	t.Run("exactly two thirds is not a quorum", func(t *testing.T) {
+		q := newQuorumState(powerTableOf(big.NewInt(1), big.NewInt(1), big.NewInt(1)), nil)
+		q.Receive(0, a, nil)
+		q.Receive(1, a, nil)
+		require.False(t, q.HasQuorumAgreement(a.Head().CID))
+		require.False(t, q.ReceivedFromQuorum())
+		q.Receive(2, a, nil)
+		require.True(t, q.HasQuorumAgreement(a.Head().CID))
+		require.True(t, q.ReceivedFromQuorum())
+	})
+
+	t.Run("senders of different values count towards quorum of senders", func(t *testing.T) {
+		q := newQuorumState(powerTableOf(big.NewInt(3), big.NewInt(3), big.NewInt(3)), nil)
+		q.Receive(0, a, nil)
+		q.Receive(1, b, nil)
+		require.False(t, q.ReceivedFromQuorum())
+		q.Receive(2, b, nil)
+		require.True(t, q.ReceivedFromQuorum())
+		require.False(t, q.HasQuorumAgreement(a.Head().CID))
+		require.False(t, q.HasQuorumAgreement(b.Head().CID))
+	})
+
+	t.Run("duplicate values are not double counted", func(t *testing.T) {
+		q := newQuorumState(powerTableOf(big.NewInt(3), big.NewInt(1)), nil)
+		q.Receive(0, a, nil)
+		q.Receive(0, a, nil)
+		require.True(t, q.HasQuorumAgreement(a.Head().CID))
+		q.Receive(1, b, nil)
+		q.Receive(1, b, nil)
+		require.False(t, q.HasQuorumAgreement(b.Head().CID))
+		require.Zero(t, q.sendersTotalPower.Cmp(big.NewInt(4)))
+	})
+
+	t.Run("sender with multiple values counted once towards senders", func(t *testing.T) {
+		q := newQuorumState(powerTableOf(big.NewInt(2), big.NewInt(1)), nil)
+		q.Receive(1, a, nil)
+		q.Receive(1, b, nil)
+		require.False(t, q.ReceivedFromQuorum())
+		q.Receive(0, b, nil)
+		require.True(t, q.ReceivedFromQuorum())
+		require.True(t, q.HasQuorumAgreement(b.Head().CID))
+		require.False(t, q.HasQuorumAgreement(a.Head().CID))
+	})
+
+	t.Run("power beyond 64 bits", func(t *testing.T) {
+		unit := new(big.Int).Lsh(big.NewInt(1), 80)
+		q := newQuorumState(powerTableOf(unit, unit, unit, unit), nil)
+		q.Receive(0, a, nil)
+		q.Receive(1, a, nil)
+		require.False(t, q.HasQuorumAgreement(a.Head().CID))
+		require.False(t, q.ReceivedFromQuorum())
+		q.Receive(2, a, nil)
+		require.True(t, q.HasQuorumAgreement(a.Head().CID))
+		require.True(t, q.ReceivedFromQuorum())
+	})
+
+	t.Run("sender without power", func(t *testing.T) {
+		q := newQuorumState(powerTableOf(big.NewInt(1), big.NewInt(0)), nil)
+		q.Receive(1, a, nil)
+		require.False(t, q.ReceivedFromQuorum())
+		require.True(t, q.HasReceived(a))
+		require.False(t, q.HasQuorumAgreement(a.Head().CID))
+	})
+}
+
+func TestComputeTicketRank(t *testing.T) {
+	ticket := Ticket("ticket")
+	infinite := ComputeTicketRank(ticket, 0)
+	require.Equal(t, 0, infinite.Compare(ComputeTicketRank(Ticket("other"), 0)))
+	rank := ComputeTicketRank(ticket, 1)
+	require.NotZero(t, rank.negLog)
+	require.Equal(t, -1, rank.Compare(infinite))
+	require.Equal(t, 1, infinite.Compare(rank))
+	require.Equal(t, 0, rank.Compare(ComputeTicketRank(ticket, 1)))
+	// Rank is inversely proportional to power.
+	require.Equal(t, -1, ComputeTicketRank(ticket, 4).Compare(rank))
+	require.Equal(t, 0, ComputeTicketRank(ticket, 4).Compare(TicketRank{negLog: rank.negLog, power: 4}))
+	// Ranks are compared as exact ratios.
+	require.Equal(t, 0, ComputeTicketRank(ticket, 4).Compare(TicketRank{negLog: rank.negLog * 2, power: 8}))
+	require.Equal(t, -1, ComputeTicketRank(ticket, 4).Compare(TicketRank{negLog: rank.negLog*2 + 1, power: 8}))
+}
+
+// Ranks are computed with integer arithmetic only, so these exact values must hold on every architecture.
+func TestTicketRankVectors(t *testing.T) {
+	for _, test := range []struct {
+		ticket string
+		negLog uint64
+	}{
+		{"", 12182759058714698},
+		{"ticket", 264899522106444388},
+		{"f3", 99431647584621747},
+		{"another ticket", 87733531628156763},
+	} {
+		require.Equal(t, TicketRank{negLog: test.negLog, power: 7}, ComputeTicketRank(Ticket(test.ticket), 7), test.ticket)
+	}
+	for m, log := range map[uint64]uint64{
+		1:                 0,
+		2:                 72057594037927936,
+		3:                 114208584442304135,
+		12345678901234567: 3851828290817625587,
+		1 << 62:           4467570830351532032,
+		1 << 63:           4539628424389459968,
+	} {
+		require.Equal(t, log, log2Fixed(m), m)
+		// Within rounding of the floating point logarithm.
+		require.InDelta(t, math.Log2(float64(m)), float64(log)/(1<<ticketRankFractionBits), 1e-12, m)
+	}
+}
+
+func TestConvergeStatePowerWeighted(t *testing.T) {
+	a := ECChain{NewTipSet(1, CIDOf([]byte("a")), 1)}
+	b := ECChain{NewTipSet(1, CIDOf([]byte("b")), 1)}
+	table := powerTableOf(big.NewInt(3), big.NewInt(1))
+	power0 := ScalePower(table.Entries[0].Power, table.Total, TicketPowerScale)
+	power1 := ScalePower(table.Entries[1].Power, table.Total, TicketPowerScale)
+
+	t.Run("selection is proportional to power", func(t *testing.T) {
+		const trials = 4000
+		wins := 0
+		for n := 0; n < trials; n++ {
+			c := newConvergeState()
+			c.Receive(0, a, Ticket(fmt.Sprintf("0-%d", n)), power0)
+			c.Receive(1, b, Ticket(fmt.Sprintf("1-%d", n)), power1)
+			if c.findMinTicketProposal().Eq(a) {
+				wins++
+			}
+		}
+		require.InDelta(t, 0.75, float64(wins)/trials, 0.03)
+	})
+
+	t.Run("equal ranks are broken by ticket then sender", func(t *testing.T) {
+		c := newConvergeState()
+		c.Receive(1, b, Ticket("same"), power1)
+		c.Receive(0, a, Ticket("same"), power1)
+		require.Equal(t, a, c.findMinTicketProposal())
+
+		c = newConvergeState()
+		c.Receive(0, a, Ticket("x"), 0)
+		c.Receive(1, b, Ticket("w"), 0)
+		require.Equal(t, b, c.findMinTicketProposal())
+	})
+
+	t.Run("first value from each sender is retained", func(t *testing.T) {
+		c := newConvergeState()
+		c.Receive(0, a, Ticket("t"), power0)
+		c.Receive(0, b, Ticket("t"), power0)
+		require.Equal(t, a, c.findMinTicketProposal())
+	})
+
+	t.Run("no values", func(t *testing.T) {
+		require.True(t, newConvergeState().findMinTicketProposal().IsZero())
+	})
+}
+
+// A network which discards everything.
+type nopNetwork struct{}
+
+func (nopNetwork) Broadcast(*GMessage)                    {}
+func (nopNetwork) Time() float64                          { return 0 }
+func (nopNetwork) SetAlarm(ActorID, Alarm, float64)       {}
+func (nopNetwork) CancelAlarm(ActorID, Alarm)             {}
+func (nopNetwork) Log(format string, args ...interface{}) {}
+
+func TestAcceptableChains(t *testing.T) {
+	base := NewTipSet(1, CIDOf([]byte("base")), 1)
+	input := NewChain(base, NewTipSet(2, CIDOf([]byte("a")), 2))
+	i := newInstance(GraniteConfig{MaxAcceptableChains: 2}, newSendGuard(0, nopNetwork{}, nil), nil, nil,
+		&LinearTimeout{}, 0, 0, input, NewPowerTable(), nil, nil, nil, &QueueStats{}, nil, nil)
+
+	fork := NewChain(base, NewTipSet(2, CIDOf([]byte("b")), 2))
+	require.True(t, i.isAcceptable(input))
+	require.True(t, i.isAcceptable(input.BaseChain()))
+	require.False(t, i.isAcceptable(fork))
+
+	// A chain received after the instance began becomes acceptable, along with its prefixes.
+	i.ReceiveAcceptable(fork.Extend(CIDOf([]byte("c"))))
+	require.True(t, i.isAcceptable(fork))
+	require.True(t, i.isAcceptable(input))
+
+	// A chain from a different base is ignored.
+	other := NewChain(NewTipSet(1, CIDOf([]byte("other")), 1), NewTipSet(2, CIDOf([]byte("d")), 2))
+	i.ReceiveAcceptable(other)
+	require.False(t, i.isAcceptable(other))
+
+	// Only the most recent chains are retained along with the input, and the chain from which
+	// the current proposal is taken.
+	later := func(j int) ECChain {
+		return NewChain(base, NewTipSet(2, CIDOf([]byte{byte(j)}), 2))
+	}
+	i.proposal = fork
+	i.ReceiveAcceptable(later(0))
+	i.ReceiveAcceptable(later(1))
+	require.Len(t, i.acceptable, 3)
+	require.True(t, i.isAcceptable(input))
+	require.True(t, i.isAcceptable(fork))
+	require.False(t, i.isAcceptable(later(0)))
+	require.True(t, i.isAcceptable(later(1)))
+
+	i.proposal = input
+	i.ReceiveAcceptable(later(2))
+	require.Len(t, i.acceptable, 3)
+	require.True(t, i.isAcceptable(input))
+	require.False(t, i.isAcceptable(fork))
+	require.True(t, i.isAcceptable(later(1)))
+	require.True(t, i.isAcceptable(later(2)))
+	require.True(t, i.isAcceptable(input.BaseChain()))
+}
+
+// A chain store holding a single chain, which is the heaviest.
+type singleChainStore ECChain
+
+func (s singleChainStore) GetTipSet(cid CID) (TipSet, error) {
+	for _, ts := range s {
+		if ts.CID == cid {
+			return ts, nil
+		}
+	}
+	return TipSet{}, fmt.Errorf("tipset %s not found", cid)
+}
+
+func (s singleChainStore) GetTipSetByEpoch(epoch int) (TipSet, error) {
+	for _, ts := range s {
+		if ts.Epoch == epoch {
+			return ts, nil
+		}
+	}
+	return TipSet{}, fmt.Errorf("no tipset at epoch %d", epoch)
+}
+
+func (s singleChainStore) IsAncestor(ancestor CID, descendant CID) (bool, error) {
+	a, err := s.GetTipSet(ancestor)
+	if err != nil {
+		return false, err
+	}
+	d, err := s.GetTipSet(descendant)
+	if err != nil {
+		return false, err
+	}
+	return a.Epoch < d.Epoch, nil
+}
+
+func TestAcceptableFromChainStore(t *testing.T) {
+	base := NewTipSet(1, CIDOf([]byte("base")), 1)
+	input := NewChain(base, NewTipSet(2, CIDOf([]byte("a")), 2))
+	heaviest := input.Extend(CIDOf([]byte("b"))).Extend(CIDOf([]byte("c")))
+	i := newInstance(GraniteConfig{}, newSendGuard(0, nopNetwork{}, nil), nil, nil, &LinearTimeout{}, 0, 0,
+		input, NewPowerTable(), nil, singleChainStore(heaviest), nil, &QueueStats{}, nil, nil)
+
+	// Chains in the store's heaviest chain are acceptable beyond the input.
+	require.True(t, i.isAcceptable(input))
+	require.True(t, i.isAcceptable(heaviest))
+	require.True(t, i.isAcceptable(heaviest.Prefix(2)))
+
+	// A chain with a tipset unknown to the store is not.
+	require.False(t, i.isAcceptable(heaviest.Extend(CIDOf([]byte("d")))))
+	fork := NewChain(base, NewTipSet(2, CIDOf([]byte("e")), 2))
+	require.False(t, i.isAcceptable(fork))
+	// Nor is a chain of stored tipsets which differ from those in the store.
+	altered := append(ECChain{}, heaviest...)
+	altered[2].Weight += 1
+	require.False(t, i.isAcceptable(altered))
+	// Nor is a chain skipping from a tipset to one which isn't its descendant.
+	require.False(t, i.isAcceptable(ECChain{base, heaviest[2], heaviest[1]}))
+	// Nor is a chain skipping over a tipset of the heaviest chain.
+	require.False(t, i.isAcceptable(ECChain{base, heaviest[2]}))
+
+	// A chain may skip the epochs of null rounds.
+	withNull := NewChain(base, NewTipSet(4, CIDOf([]byte("f")), 2))
+	i.chainStore = singleChainStore(withNull)
+	require.True(t, i.isAcceptable(withNull))
+}
+
+type failingJournal struct {
+	memJournal
+}
+
+func (j *failingJournal) Append(*JournalEntry) error {
+	return errors.New("disk full")
+}
+
+func TestInstanceStopsSendingOnJournalFailure(t *testing.T) {
+	base := NewTipSet(1, CIDOf([]byte("base")), 1)
+	input := NewChain(base, NewTipSet(2, CIDOf([]byte("a")), 2))
+	power := NewPowerTable()
+	power.Add(0, big.NewInt(1), []byte("key0"))
+	power.Add(1, big.NewInt(1), []byte("key1"))
+	ntwk := &recordingNetwork{}
+	var failures []error
+	config := GraniteConfig{Delta: 10}
+	i := newInstance(config, newSendGuard(0, ntwk, nil), NewFakeVRF(), payloadSigner{}, &LinearTimeout{Delta: config.Delta}, 0, 0,
+		input, power, nil, nil, &failingJournal{}, &QueueStats{}, nil, func(err error) {
+			failures = append(failures, err)
+		})
+
+	// The QUALITY message fails to journal, so is not sent, and the failure is reported.
+	i.Start()
+	require.Empty(t, ntwk.sent)
+	require.Len(t, failures, 1)
+
+	// Nothing further is sent, nor reported again.
+	i.broadcast(PREPARE, input, nil, nil)
+	require.Empty(t, ntwk.sent)
+	require.Len(t, failures, 1)
+}
+
+// A signer whose signatures are the payload and whose aggregates concatenate the signatures,
+// counting the aggregates verified.
+type aggregatingSigner struct {
+	payloadSigner
+	verified int
+}
+
+func (s *aggregatingSigner) Aggregate(_ []PubKey, sigs [][]byte) ([]byte, error) {
+	return bytes.Join(sigs, nil), nil
+}
+
+func (s *aggregatingSigner) VerifyAggregate(payload []byte, aggSig []byte, pubKeys []PubKey) bool {
+	s.verified += 1
+	return bytes.Equal(aggSig, bytes.Repeat(payload, len(pubKeys)))
+}
+
+func TestConvergeJustification(t *testing.T) {
+	base := NewTipSet(1, CIDOf([]byte("base")), 1)
+	input := NewChain(base, NewTipSet(2, CIDOf([]byte("a")), 2))
+	power := NewPowerTable()
+	for id := ActorID(0); id < 4; id++ {
+		power.Add(id, big.NewInt(1), []byte(fmt.Sprintf("key%d", id)))
+	}
+	prepared := &Justification{Round: 0, Step: PREPARE, Value: input, Signers: NewBitfield(1, 2, 3),
+		Signature: bytes.Repeat(SignaturePayload(0, 0, PREPARE, input), 3)}
+	converge := func(sender ActorID, justification *Justification) *GMessage {
+		vrf := NewFakeVRF()
+		_, pubKey := power.Get(sender)
+		return &GMessage{Sender: sender, Instance: 0, Round: 1, Step: CONVERGE, Value: input,
+			Ticket: vrf.MakeTicket(nil, 0, 1, pubKey), Justification: justification, Signature: []byte("sig")}
+	}
+
+	for _, test := range []struct {
+		name          string
+		justification *Justification
+		justified     bool
+		verified      int
+	}{
+		{"valid", prepared, true, 1},
+		{"absent", nil, false, 0},
+		{"forged", &Justification{Round: 0, Step: PREPARE, Value: input, Signers: NewBitfield(1, 2, 3),
+			Signature: []byte("forged")}, false, 2},
+		{"minority", &Justification{Round: 0, Step: PREPARE, Value: input, Signers: NewBitfield(1, 2),
+			Signature: bytes.Repeat(SignaturePayload(0, 0, PREPARE, input), 2)}, false, 0},
+		{"wrong round", &Justification{Round: 1, Step: PREPARE, Value: input, Signers: NewBitfield(1, 2, 3),
+			Signature: bytes.Repeat(SignaturePayload(0, 1, PREPARE, input), 3)}, false, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			signer := &aggregatingSigner{}
+			config := GraniteConfig{Delta: 10}
+			i := newInstance(config, newSendGuard(0, nopNetwork{}, nil), NewFakeVRF(), signer, &LinearTimeout{Delta: config.Delta},
+				0, 0, input, power, nil, nil, nil, &QueueStats{}, nil, nil)
+			i.Start()
+
+			// A CONVERGE for a round whose PREPARE quorum this instance hasn't observed is justified
+			// only by a valid justification, and is otherwise held if unjustified or dropped if invalid.
+			i.Receive(converge(1, test.justification))
+			require.Equal(t, test.justified, i.isJustified(converge(1, nil)))
+			if test.justification == nil {
+				require.Equal(t, 1, i.pending.Len())
+			} else {
+				require.Zero(t, i.pending.Len())
+			}
+
+			// A justification is verified once with each message carrying it, and not again once valid,
+			// however many messages are received subsequently.
+			i.Receive(converge(2, test.justification))
+			i.Receive(&GMessage{Sender: 3, Instance: 0, Round: 0, Step: QUALITY, Value: input, Signature: []byte("sig")})
+			require.Equal(t, test.verified, signer.verified)
+		})
+	}
+}
+
+// A signer which fails to aggregate signatures.
+type failingAggregator struct {
+	aggregatingSigner
+}
+
+func (s *failingAggregator) Aggregate([]PubKey, [][]byte) ([]byte, error) {
+	return nil, errors.New("aggregation failed")
+}
+
+func TestNoDecisionWithoutAggregate(t *testing.T) {
+	base := NewTipSet(1, CIDOf([]byte("base")), 1)
+	input := NewChain(base, NewTipSet(2, CIDOf([]byte("a")), 2))
+	power := NewPowerTable()
+	for id := ActorID(0); id < 4; id++ {
+		power.Add(id, big.NewInt(1), []byte(fmt.Sprintf("key%d", id)))
+	}
+	config := GraniteConfig{Delta: 10}
+	i := newInstance(config, newSendGuard(0, nopNetwork{}, nil), NewFakeVRF(), &failingAggregator{},
+		&LinearTimeout{Delta: config.Delta}, 0, 0, input, power, nil, nil, nil, &QueueStats{}, nil, nil)
+	i.Start()
+
+	// A strong quorum of COMMIT doesn't decide if its signatures can't be aggregated into a certificate.
+	for _, step := range []string{PREPARE, COMMIT} {
+		for sender := ActorID(1); sender < 4; sender++ {
+			i.Receive(&GMessage{Sender: sender, Instance: 0, Round: 0, Step: step, Value: input,
+				Signature: []byte("sig")})
+		}
+	}
+	require.True(t, i.roundState(0).committed.HasQuorumAgreement(input.Head().CID))
+	require.False(t, i.decided())
+	require.Nil(t, i.certificate())
+}
@@ -0,0 +1,98 @@
+package f3
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when refusing to send a message which conflicts with one this participant already sent.
+var ErrSelfEquivocation = errors.New("self-equivocation")
+
+// A guard between a participant and the network, which refuses to send conflicting messages.
+// The guard records each message sent, and refuses to send a message with a different value
+// from one already sent for the same instance, round and step (or the same instance, for DECIDE),
+// whatever the participant's state machine does. Re-sending a message with the same value is allowed.
+// Messages restored from a journal are recorded too, so the guard holds across restarts.
+type sendGuard struct {
+	// The guarded network, to which all other methods are delegated.
+	Network
+	id ActorID
+	// The first message sent for each instance, round and step.
+	sent map[sendKey]*GMessage
+	// Invoked with the error for each message refused, if not nil.
+	onRefusal func(err error)
+}
+
+type sendKey struct {
+	instance int
+	round    int
+	step     string
+}
+
+func newSendGuard(id ActorID, ntwk Network, onRefusal func(err error)) *sendGuard {
+	return &sendGuard{Network: ntwk, id: id, sent: map[sendKey]*GMessage{}, onRefusal: onRefusal}
+}
+
+// Checks whether a message may be sent, returning an error wrapping ErrSelfEquivocation if
+// it conflicts with a message already sent.
+func (g *sendGuard) Check(msg *GMessage) error {
+	if prev, ok := g.sent[keyOf(msg)]; ok && !prev.Value.Eq(msg.Value) {
+		return fmt.Errorf("%w: %s conflicts with sent %s", ErrSelfEquivocation, msg, prev)
+	}
+	return nil
+}
+
+// Records a message as sent, and sends it, unless it conflicts with a message already sent.
+func (g *sendGuard) Send(msg *GMessage) error {
+	if err := g.Restore(msg); err != nil {
+		return err
+	}
+	g.Network.Broadcast(msg)
+	return nil
+}
+
+// Records a message as sent without sending it, such as one sent before a restart,
+// unless it conflicts with a message already sent.
+func (g *sendGuard) Restore(msg *GMessage) error {
+	if err := g.Check(msg); err != nil {
+		return err
+	}
+	key := keyOf(msg)
+	if _, ok := g.sent[key]; !ok {
+		g.sent[key] = msg
+	}
+	return nil
+}
+
+// Sends a message, or reports an error if it conflicts with a message already sent.
+func (g *sendGuard) Broadcast(msg *GMessage) {
+	if err := g.Send(msg); err != nil {
+		g.refuse(err)
+	}
+}
+
+// Reports the refusal to send a message, which indicates a bug in the participant.
+func (g *sendGuard) refuse(err error) {
+	g.Log("P%d: ‼️ refusing to send: %s", g.id, err)
+	if g.onRefusal != nil {
+		g.onRefusal(err)
+	}
+}
+
+// Forgets messages sent in instances before some instance.
+// The participant never sends messages for an instance before its last decided one.
+func (g *sendGuard) Prune(before int) {
+	for key := range g.sent {
+		if key.instance < before {
+			delete(g.sent, key)
+		}
+	}
+}
+
+func keyOf(msg *GMessage) sendKey {
+	if msg.Step == DECIDE {
+		// A participant decides an instance only once, whichever round it decides in.
+		return sendKey{msg.Instance, 0, DECIDE}
+	}
+	return sendKey{msg.Instance, msg.Round, msg.Step}
+}
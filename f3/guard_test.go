@@ -0,0 +1,122 @@
+package f3
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNetwork struct {
+	nopNetwork
+	sent []*GMessage
+}
+
+func (n *recordingNetwork) Broadcast(msg *GMessage) {
+	n.sent = append(n.sent, msg)
+}
+
+type memJournal struct {
+	entries []*JournalEntry
+}
+
+func (j *memJournal) Append(entry *JournalEntry) error {
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+func (j *memJournal) Entries() ([]*JournalEntry, error) {
+	return j.entries, nil
+}
+
+func (j *memJournal) Rewrite(entries []*JournalEntry) error {
+	j.entries = entries
+	return nil
+}
+
+// A signer whose signatures are the payload, and which accepts any signature.
+// Aggregation is not implemented.
+type payloadSigner struct {
+	SignerVerifier
+}
+
+func (payloadSigner) Sign(_ PubKey, msg []byte) []byte {
+	return msg
+}
+
+func (payloadSigner) Verify(PubKey, []byte, []byte) bool {
+	return true
+}
+
+func TestSendGuard(t *testing.T) {
+	base := NewTipSet(1, CIDOf([]byte("base")), 1)
+	a := NewChain(base, NewTipSet(2, CIDOf([]byte("a")), 2))
+	b := NewChain(base, NewTipSet(2, CIDOf([]byte("b")), 2))
+	ntwk := &recordingNetwork{}
+	var refused []error
+	guard := newSendGuard(0, ntwk, func(err error) { refused = append(refused, err) })
+
+	require.NoError(t, guard.Send(&GMessage{Instance: 1, Round: 0, Step: PREPARE, Value: a}))
+	// The same value may be sent again, but not a different one.
+	require.NoError(t, guard.Send(&GMessage{Instance: 1, Round: 0, Step: PREPARE, Value: a}))
+	require.ErrorIs(t, guard.Send(&GMessage{Instance: 1, Round: 0, Step: PREPARE, Value: b}), ErrSelfEquivocation)
+	require.ErrorIs(t, guard.Send(&GMessage{Instance: 1, Round: 0, Step: PREPARE}), ErrSelfEquivocation)
+	guard.Broadcast(&GMessage{Instance: 1, Round: 0, Step: PREPARE, Value: b})
+	require.Len(t, ntwk.sent, 2)
+	require.Len(t, refused, 1)
+	require.ErrorIs(t, refused[0], ErrSelfEquivocation)
+
+	// Other instances, rounds and steps are independent.
+	require.NoError(t, guard.Send(&GMessage{Instance: 2, Round: 0, Step: PREPARE, Value: b}))
+	require.NoError(t, guard.Send(&GMessage{Instance: 1, Round: 1, Step: PREPARE, Value: b}))
+	require.NoError(t, guard.Send(&GMessage{Instance: 1, Round: 0, Step: COMMIT, Value: b}))
+
+	// An instance is decided only once, in any round.
+	require.NoError(t, guard.Send(&GMessage{Instance: 1, Round: 2, Step: DECIDE, Value: a}))
+	require.NoError(t, guard.Send(&GMessage{Instance: 1, Round: 3, Step: DECIDE, Value: a}))
+	require.ErrorIs(t, guard.Send(&GMessage{Instance: 1, Round: 3, Step: DECIDE, Value: b}), ErrSelfEquivocation)
+
+	// Restored messages are guarded without being sent.
+	require.NoError(t, guard.Restore(&GMessage{Instance: 3, Round: 0, Step: QUALITY, Value: a}))
+	require.ErrorIs(t, guard.Check(&GMessage{Instance: 3, Round: 0, Step: QUALITY, Value: b}), ErrSelfEquivocation)
+	require.Len(t, ntwk.sent, 7)
+
+	// Pruned instances are forgotten.
+	guard.Prune(2)
+	require.NoError(t, guard.Check(&GMessage{Instance: 1, Round: 0, Step: PREPARE, Value: b}))
+	require.ErrorIs(t, guard.Check(&GMessage{Instance: 2, Round: 0, Step: PREPARE, Value: a}), ErrSelfEquivocation)
+}
+
+func TestInstanceRefusesSelfEquivocation(t *testing.T) {
+	base := NewTipSet(1, CIDOf([]byte("base")), 1)
+	a := NewChain(base, NewTipSet(2, CIDOf([]byte("a")), 2))
+	b := NewChain(base, NewTipSet(2, CIDOf([]byte("b")), 2))
+	power := NewPowerTable()
+	power.Add(0, big.NewInt(1), []byte("key0"))
+	power.Add(1, big.NewInt(1), []byte("key1"))
+
+	// An instance resumed from its journal after sending PREPARE for one value.
+	ntwk := &recordingNetwork{}
+	journal := &memJournal{}
+	sent := &JournalEntry{Kind: JournalSend, Instance: 0, Proposal: a, Value: a,
+		Message: &GMessage{Sender: 0, Instance: 0, Round: 0, Step: PREPARE, Value: a, Signature: []byte("sig")}}
+	config := GraniteConfig{Delta: 10}
+	var refused []error
+	guard := newSendGuard(0, ntwk, func(err error) { refused = append(refused, err) })
+	i := newInstance(config, guard, NewFakeVRF(), payloadSigner{}, &LinearTimeout{Delta: config.Delta}, 0, 0,
+		a, power, nil, nil, journal, &QueueStats{}, nil, nil)
+	i.Resume([]*JournalEntry{sent})
+	require.Equal(t, []*GMessage{sent.Message}, ntwk.sent)
+
+	// A bug sending PREPARE for another value is refused and reported, and neither journaled nor sent.
+	i.broadcast(PREPARE, b, nil, nil)
+	require.Empty(t, journal.entries)
+	require.Equal(t, []*GMessage{sent.Message}, ntwk.sent)
+	require.Len(t, refused, 1)
+	require.ErrorIs(t, refused[0], ErrSelfEquivocation)
+
+	// Sending the same value again is allowed.
+	i.broadcast(PREPARE, a, nil, nil)
+	require.Len(t, journal.entries, 1)
+	require.Len(t, ntwk.sent, 2)
+}
@@ -0,0 +1,79 @@
+package f3
+
+// Kinds of journal entry.
+const (
+	// An instance began with some input.
+	JournalStart = "START"
+	// An instance moved to a new state, in which it sent a message.
+	JournalSend = "SEND"
+	// An instance was decided.
+	JournalDecide = "DECIDE"
+)
+
+// Durable storage of a participant's protocol state.
+// A participant journals each instance it begins, and each message it sends along with the state
+// in which it sent it, before broadcasting.
+// After a restart, a participant restored from its journal resumes where it left off,
+// rather than sending messages conflicting with those it sent before.
+type Journal interface {
+	// Appends an entry, returning only once the entry is durable.
+	Append(entry *JournalEntry) error
+	// Returns all durable entries, in order of appending.
+	Entries() ([]*JournalEntry, error)
+	// Replaces all entries, atomically, returning only once the replacement is durable.
+	Rewrite(entries []*JournalEntry) error
+}
+
+// A record of a participant's protocol state.
+type JournalEntry struct {
+	// The kind of entry.
+	Kind string
+	// The instance to which the entry pertains.
+	Instance int
+	// The input of a started instance.
+	Input ECChain
+	// The proposal and value of an instance when it sent a message.
+	Proposal ECChain
+	Value    ECChain
+	// The message sent. The instance's round and phase are the message's round and step.
+	Message *GMessage
+	// The certificate of a decided instance.
+	Certificate *FinalityCertificate
+}
+
+// The latest state of a participant recorded in a journal.
+type journalState struct {
+	// Certificate of the last decided instance, or nil.
+	decided *FinalityCertificate
+	// The start of an instance after the last decided, or nil.
+	started *JournalEntry
+	// Messages sent by the started instance, in order.
+	sent []*JournalEntry
+}
+
+// Reads the latest state from journal entries.
+func readJournal(entries []*JournalEntry) journalState {
+	var state journalState
+	for _, entry := range entries {
+		switch entry.Kind {
+		case JournalDecide:
+			if state.decided == nil || entry.Instance > state.decided.Instance {
+				state.decided = entry.Certificate
+			}
+			if state.started != nil && state.started.Instance <= entry.Instance {
+				state.started = nil
+				state.sent = nil
+			}
+		case JournalStart:
+			if state.decided == nil || entry.Instance > state.decided.Instance {
+				state.started = entry
+				state.sent = nil
+			}
+		case JournalSend:
+			if state.started != nil && entry.Instance == state.started.Instance {
+				state.sent = append(state.sent, entry)
+			}
+		}
+	}
+	return state
+}
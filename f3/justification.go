@@ -0,0 +1,57 @@
+package f3
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Proof that a message's value was allowed, in the form of an aggregate of a strong quorum of prior messages.
+// A justification lets a receiver validate a message without itself having observed the prior messages.
+// The justifying messages are from the same instance as the message they justify.
+type Justification struct {
+	// The round of the justifying messages.
+	Round int
+	// The step of the justifying messages.
+	Step string
+	// The value of the justifying messages.
+	Value ECChain
+	// The senders of the justifying messages, indexed by power table order.
+	Signers Bitfield
+	// Aggregate of the signers' signatures over the justifying messages' payload, in power table order.
+	Signature []byte
+}
+
+// Checks that the justification is signed by a strong quorum of the power table,
+// for messages in some instance.
+func (j *Justification) Verify(instance int, powerTable PowerTable, aggregator Aggregator) error {
+	power, pubKeys, err := signersPower(powerTable, j.Signers)
+	if err != nil {
+		return err
+	}
+	if !IsStrongQuorum(power, powerTable.Total) {
+		return fmt.Errorf("signers power %d is not a strong quorum of %d", power, powerTable.Total)
+	}
+	payload := SignaturePayload(instance, j.Round, j.Step, j.Value)
+	if !aggregator.VerifyAggregate(payload, j.Signature, pubKeys) {
+		return fmt.Errorf("invalid aggregate signature")
+	}
+	return nil
+}
+
+func (j *Justification) String() string {
+	return fmt.Sprintf("JUSTIFICATION(%s %d %s, %d signers)", j.Step, j.Round, &j.Value, j.Signers.Count())
+}
+
+// Returns the total power and public keys of the signers in a bitfield indexing a power table.
+func signersPower(powerTable PowerTable, signers Bitfield) (*big.Int, []PubKey, error) {
+	power := new(big.Int)
+	var pubKeys []PubKey
+	for _, idx := range signers.Indices() {
+		if idx >= len(powerTable.Entries) {
+			return nil, nil, fmt.Errorf("signer index %d out of range", idx)
+		}
+		power.Add(power, powerTable.Entries[idx].Power)
+		pubKeys = append(pubKeys, powerTable.Entries[idx].PubKey)
+	}
+	return power, pubKeys, nil
+}
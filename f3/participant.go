@@ -1,13 +1,35 @@
 package f3
 
+import "fmt"
+
 // An F3 participant runs repeated instances of Granite to finalise longer chains.
 type Participant struct {
 	id     ActorID
 	config GraniteConfig
 	ntwk   Network
 	vrf    VRFer
+	signer SignerVerifier
+	// Policy for phase timeouts, retained across instances.
+	timeouts TimeoutPolicy
+	// Sources of each instance's power table and beacon.
+	powerTables PowerTableProvider
+	beacons     BeaconProvider
+	// The local EC chain store, consulted for acceptable chains. May be nil.
+	chainStore ChainStore
+	// Journal of instances begun, messages sent and decisions, for restoring after a restart. May be nil.
+	journal Journal
+	// Guard through which all messages are sent, refusing any which conflict with those already sent.
+	guard *sendGuard
 
-	mpool []*GMessage
+	// Messages queued for future instances.
+	mpool *messagePool
+	// Power table of the latest instance begun or decided, against which the senders of messages
+	// for future instances are checked. Empty before any instance begins.
+	powerTable PowerTable
+	// The instance to which powerTable belongs.
+	powerTableInstance int
+	// Counters of messages dropped from the message pool and instances' pending queues.
+	stats QueueStats
 	// Chain to use as input for the next Granite instance.
 	nextChain ECChain
 	// Instance identifier for the next Granite instance.
@@ -18,16 +40,55 @@ type Participant struct {
 	finalised TipSet
 	// The round number at which the last instance was decided.
 	finalisedRound int
+	// Certificate for the last decided Granite instance.
+	certificate *FinalityCertificate
+	// DECIDE message for the last decided instance, re-sent for participants which are still running it.
+	// Nil if this participant is not in the instance's power table.
+	decide *GMessage
+	// Time at which the DECIDE message was last sent.
+	decideTime float64
+	// Callbacks invoked with the certificate for each decision.
+	decisionListeners []func(cert *FinalityCertificate)
+	// Callbacks invoked with evidence of each equivocation detected.
+	equivocationListeners []func(evidence *EquivocationEvidence)
+	// The error which halted the participant, if any. A halted participant ignores all input.
+	failure error
+	// Callbacks invoked with the error which halts the participant.
+	failureListeners []func(err error)
+	// Callbacks invoked with the error for each message the send guard refused.
+	selfEquivocationListeners []func(err error)
 }
 
-func NewParticipant(id ActorID, config GraniteConfig, ntwk Network, vrf VRFer) *Participant {
-	return &Participant{id: id, config: config, ntwk: ntwk, vrf: vrf}
+// Creates a participant, returning an error wrapping ErrInvalidConfig if the configuration is invalid.
+func NewParticipant(id ActorID, config GraniteConfig, ntwk Network, vrf VRFer, signer SignerVerifier,
+	powerTables PowerTableProvider, beacons BeaconProvider) (*Participant, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	timeouts, err := NewTimeoutPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+	config = config.withDefaults()
+	p := &Participant{id: id, config: config, ntwk: ntwk, vrf: vrf, signer: signer,
+		timeouts: timeouts, powerTables: powerTables, beacons: beacons}
+	p.guard = newSendGuard(id, ntwk, p.reportSelfEquivocation)
+	p.mpool = newMessagePool(newQueueBounds(config, &p.stats))
+	return p, nil
 }
 
 func (p *Participant) ID() ActorID {
 	return p.id
 }
 
+// Returns the ID of the running instance, or of the next instance if none is running.
+func (p *Participant) CurrentInstance() int {
+	if p.granite != nil {
+		return p.granite.instanceID
+	}
+	return p.nextInstance
+}
+
 func (p *Participant) CurrentRound() int {
 	if p.granite == nil {
 		return -1
@@ -38,46 +99,416 @@ func (p *Participant) Finalised() (TipSet, int) {
 	return p.finalised, p.finalisedRound
 }
 
+// Returns the certificate for the last decided instance, or nil if no instance has been decided.
+func (p *Participant) FinalityCertificate() *FinalityCertificate {
+	return p.certificate
+}
+
+// Returns the power table of the latest instance begun or decided, and that instance.
+// The power table is empty before any instance begins.
+func (p *Participant) PowerTable() (PowerTable, int) {
+	return p.powerTable, p.powerTableInstance
+}
+
+// Sets the local EC chain store, which subsequent instances consult to decide which chains are acceptable.
+// Without a chain store, only canonical chains received by the participant are acceptable.
+func (p *Participant) SetChainStore(store ChainStore) {
+	p.chainStore = store
+}
+
+// Sets the journal to which the participant writes its protocol state before sending each message,
+// and restores the participant from the state already journaled, if any.
+// A restored participant resumes from its last journaled decision, and resumes any subsequent instance
+// it had begun with the same input, round, phase and messages.
+// This must be called before the participant receives any chain or message.
+func (p *Participant) SetJournal(journal Journal) error {
+	entries, err := journal.Entries()
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	p.journal = journal
+	state := readJournal(entries)
+	if cert := state.decided; cert != nil {
+		powerTable, err := p.powerTables.GetPowerTable(*cert.Value.Base(), p.config.PowerTableLookback)
+		if err != nil {
+			return fmt.Errorf("no power table for journaled %s: %w", cert.Head(), err)
+		}
+		p.nextInstance = cert.Instance + 1
+		p.record(cert, powerTable)
+		if p.decide != nil {
+			if err := p.guard.Restore(p.decide); err != nil {
+				return fmt.Errorf("journaled decision: %w", err)
+			}
+		}
+	}
+	if started := state.started; started != nil {
+		p.nextInstance = started.Instance
+		p.beginInstance(started.Input, state.sent)
+		p.handleDecision()
+	}
+	return nil
+}
+
+// Returns the counts of messages dropped to bound the memory used by queued messages.
+func (p *Participant) QueueStats() QueueStats {
+	return p.stats
+}
+
+// Registers a callback to be invoked with the certificate for each subsequent decision.
+func (p *Participant) OnDecision(listener func(cert *FinalityCertificate)) {
+	p.decisionListeners = append(p.decisionListeners, listener)
+}
+
+// Registers a callback to be invoked with evidence of each subsequently detected equivocation.
+// A sender is reported at most once for each instance, round and step.
+func (p *Participant) OnEquivocation(listener func(evidence *EquivocationEvidence)) {
+	p.equivocationListeners = append(p.equivocationListeners, listener)
+}
+
+// Registers a callback to be invoked with the error if the participant halts.
+// A participant halts if it fails to journal its state, since it can't then safely send any further message.
+// It must be restarted from its journal.
+func (p *Participant) OnFailure(listener func(err error)) {
+	p.failureListeners = append(p.failureListeners, listener)
+}
+
+// Registers a callback to be invoked with an error wrapping ErrSelfEquivocation whenever the participant
+// attempts to send a message conflicting with one it already sent, which the send guard refuses.
+// This indicates a bug in the participant, which never sends conflicting messages if correct.
+func (p *Participant) OnSelfEquivocation(listener func(err error)) {
+	p.selfEquivocationListeners = append(p.selfEquivocationListeners, listener)
+}
+
+// Returns the error which halted the participant, or nil if it is running.
+func (p *Participant) Failure() error {
+	return p.failure
+}
+
 // Receives a new canonical EC chain for the instance.
 // This becomes the instance's preferred value to finalise.
-func (p *Participant) ReceiveCanonicalChain(chain ECChain, power PowerTable, beacon []byte) {
+// If no instance is running, this begins the next instance.
+// Otherwise, the running instance may also vote for the part of the chain from its base.
+func (p *Participant) ReceiveCanonicalChain(chain ECChain) {
+	if p.failure != nil {
+		return
+	}
 	p.nextChain = chain
 	if p.granite == nil {
-		p.granite = newInstance(p.config, p.ntwk, p.vrf, p.id, p.nextInstance, chain, power, beacon)
-		p.nextInstance += 1
-		p.granite.Start()
+		p.tryNewInstance()
+		p.handleDecision()
+	} else if acceptable := chain.From(p.granite.input.Base()); !acceptable.IsZero() {
+		p.granite.ReceiveAcceptable(acceptable)
 	}
 }
 
 // Receives a Granite message from some other participant.
 func (p *Participant) ReceiveMessage(msg *GMessage) {
-	if p.granite != nil && msg.Instance == p.granite.instanceID {
+	if p.failure != nil {
+		return
+	} else if msg.Step == DECIDE {
+		p.receiveDecide(msg)
+	} else if p.granite != nil && msg.Instance == p.granite.instanceID {
 		p.granite.Receive(msg)
 		p.handleDecision()
+	} else if p.config.MaxFutureInstances > 0 && msg.Instance > p.CurrentInstance()+p.config.MaxFutureInstances {
+		p.ntwk.Log("P%d: dropping %s beyond instance window", p.id, msg)
+		p.stats.DroppedFuture += 1
+	} else if msg.Instance >= p.nextInstance && !p.isKnownSender(msg.Sender) {
+		p.ntwk.Log("P%d: dropping %s from sender not in power table", p.id, msg)
+		p.stats.DroppedUnknownSender += 1
 	} else if msg.Instance >= p.nextInstance {
-		// Queue messages for later instances
-		p.mpool = append(p.mpool, msg)
+		// Queue messages for later instances, whose signatures can't be verified until they begin.
+		p.mpool.Add(msg)
+	} else if p.certificate != nil && msg.Instance == p.certificate.Instance {
+		// The sender is still running the last decided instance, and may have missed its DECIDE.
+		p.rebroadcastDecide()
 	}
 }
 
-func (p *Participant) ReceiveAlarm(payload string) {
-	// TODO include instance ID in alarm message, and filter here.
-	if p.granite != nil {
-		p.granite.ReceiveAlarm(payload)
+// Receives an alarm, ignoring it unless it was set by the current instance.
+func (p *Participant) ReceiveAlarm(alarm Alarm) {
+	if p.failure == nil && p.granite != nil && alarm.Instance == p.granite.instanceID {
+		p.granite.ReceiveAlarm(alarm)
 		p.handleDecision()
 	}
 }
 
+// Receives a certificate of some instance's decision, such as one fetched from another participant
+// after falling behind.
+// A valid certificate for the current or a later instance is accepted as that instance's decision:
+// any running instance is abandoned, the certified value is finalised, and the following instance begins.
+// A certificate which doesn't link to the local chain of decisions is rejected (see checkLinked).
+// A certificate for an earlier instance is ignored.
+func (p *Participant) ReceiveFinalityCertificate(cert *FinalityCertificate) error {
+	if p.failure != nil {
+		return p.failure
+	}
+	if cert.Instance < p.CurrentInstance() {
+		return nil
+	}
+	if cert.Value.IsZero() {
+		return fmt.Errorf("certificate for bottom")
+	}
+	if err := p.checkLinked(cert); err != nil {
+		return err
+	}
+	powerTable, err := p.powerTables.GetPowerTable(*cert.Value.Base(), p.config.PowerTableLookback)
+	if err != nil {
+		return fmt.Errorf("no power table for certificate base %s: %w", cert.Value.Base(), err)
+	}
+	if err := cert.Verify(powerTable, p.signer); err != nil {
+		return fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	p.ntwk.Log("P%d: catching up to %s", p.id, cert)
+	if p.granite != nil {
+		p.granite.cancelAlarm()
+		p.granite = nil
+	}
+	p.nextInstance = cert.Instance + 1
+	p.mpool.Prune(p.nextInstance)
+	if !p.finalise(cert, powerTable) {
+		return p.failure
+	}
+	p.tryNewInstance()
+	p.handleDecision()
+	return p.failure
+}
+
+// Checks that a certificate's base links it to the local chain of decisions.
+// A certificate for the current instance must have the same base as the running instance,
+// or extend the last finalised tipset if none is running.
+// A certificate for a later instance must have a base descending from that tipset in the chain store.
+// Before any instance has begun or been decided there is nothing to link to, and any certificate is accepted.
+func (p *Participant) checkLinked(cert *FinalityCertificate) error {
+	var anchor *TipSet
+	if p.granite != nil {
+		anchor = p.granite.input.Base()
+	} else if p.certificate != nil {
+		anchor = &p.finalised
+	} else {
+		return nil
+	}
+	base := cert.Value.Base()
+	if base.Eq(anchor) {
+		return nil
+	}
+	if cert.Instance == p.CurrentInstance() {
+		return fmt.Errorf("certificate base %s is not the current base %s", base, anchor)
+	}
+	if p.chainStore == nil {
+		return fmt.Errorf("no chain store to link certificate base %s to %s", base, anchor)
+	}
+	if ok, err := p.chainStore.IsAncestor(anchor.CID, base.CID); err != nil {
+		return fmt.Errorf("linking certificate base %s to %s: %w", base, anchor, err)
+	} else if !ok {
+		return fmt.Errorf("certificate base %s does not descend from %s", base, anchor)
+	}
+	return nil
+}
+
+// Receives a DECIDE message, which carries the strong quorum of COMMIT for its value as justification.
+// This catches up to the decision if it is for the current or a later instance.
+func (p *Participant) receiveDecide(msg *GMessage) {
+	if msg.Instance < p.CurrentInstance() {
+		return
+	}
+	if err := msg.Validate(p.config.MaxChainLength); err != nil {
+		p.ntwk.Log("P%d: dropping invalid %s: %s", p.id, msg, err)
+		return
+	}
+	j := msg.Justification
+	if j == nil || j.Step != COMMIT || j.Round != msg.Round || !j.Value.Eq(msg.Value) {
+		p.ntwk.Log("P%d: dropping unjustified %s", p.id, msg)
+		return
+	}
+	powerTable, err := p.powerTables.GetPowerTable(*msg.Value.Base(), p.config.PowerTableLookback)
+	if err != nil {
+		p.ntwk.Log("P%d: no power table for %s: %s", p.id, msg, err)
+		return
+	}
+	power, _, err := signersPower(powerTable, j.Signers)
+	if err != nil {
+		p.ntwk.Log("P%d: dropping %s: %s", p.id, msg, err)
+		return
+	}
+	cert := &FinalityCertificate{
+		Instance:     msg.Instance,
+		Value:        msg.Value,
+		Round:        j.Round,
+		Signers:      j.Signers,
+		SignersPower: power,
+		Signature:    j.Signature,
+	}
+	if err := p.ReceiveFinalityCertificate(cert); err != nil {
+		p.ntwk.Log("P%d: dropping %s: %s", p.id, msg, err)
+	}
+}
+
+// Records the decision of the current instance, if decided, and begins the next instance.
+// A new instance may itself decide immediately, so this repeats until there is no decision.
 func (p *Participant) handleDecision() {
-	if p.decided() {
-		p.finalised = *p.granite.value.Head()
-		p.finalisedRound = p.granite.round
+	for p.decided() {
+		cert := p.granite.certificate()
+		powerTable := p.granite.powerTable
 		p.granite = nil
+		if !p.finalise(cert, powerTable) {
+			return
+		}
+		p.tryNewInstance()
+	}
+}
+
+// Records a decision and notifies listeners, returning whether it did so.
+// Broadcasts a DECIDE message for the decision, so that participants which have not yet decided can catch up.
+// The journal is compacted to just the decision, since no earlier state is needed to resume.
+// If the decision fails to journal, the participant halts without sending or reporting it.
+func (p *Participant) finalise(cert *FinalityCertificate, powerTable PowerTable) bool {
+	if p.journal != nil {
+		entry := &JournalEntry{Kind: JournalDecide, Instance: cert.Instance, Certificate: cert}
+		if err := p.journal.Rewrite([]*JournalEntry{entry}); err != nil {
+			p.fail(fmt.Errorf("failed to journal decision of instance %d: %w", cert.Instance, err))
+			return false
+		}
+	}
+	p.record(cert, powerTable)
+	p.guard.Prune(cert.Instance)
+	if p.decide != nil {
+		p.guard.Broadcast(p.decide)
+	}
+	for _, listener := range p.decisionListeners {
+		listener(cert)
+	}
+	return true
+}
+
+// Records a decision, and prepares a DECIDE message for it if this participant is in the power table.
+func (p *Participant) record(cert *FinalityCertificate, powerTable PowerTable) {
+	p.finalised = *cert.Head()
+	p.finalisedRound = cert.Round
+	p.certificate = cert
+	p.powerTable = powerTable
+	p.powerTableInstance = cert.Instance
+	p.decide = nil
+	if _, pubKey := powerTable.Get(p.id); pubKey != nil {
+		p.decide = &GMessage{
+			Sender:        p.id,
+			Instance:      cert.Instance,
+			Round:         cert.Round,
+			Step:          DECIDE,
+			Value:         cert.Value,
+			Justification: cert.justification(),
+		}
+		p.decide.Signature = p.signer.Sign(pubKey, p.decide.SignaturePayload())
+	}
+	p.decideTime = p.ntwk.Time()
+}
+
+// Begins the next instance, if there is an input chain for it, and replays queued messages for it.
+// The input for every instance after the first is the part of the next chain extending the last finalised tipset.
+// No instance begins if the next chain doesn't extend the last finalised tipset,
+// until a subsequent canonical chain does.
+func (p *Participant) tryNewInstance() {
+	if p.failure != nil || p.granite != nil || p.nextChain.IsZero() {
+		return
+	}
+	input := p.nextChain
+	if p.certificate != nil {
+		input = p.nextChain.From(&p.finalised)
+		if input.IsZero() || len(input.Suffix()) == 0 {
+			return
+		}
+	}
+	if max := p.config.MaxChainLength; max > 0 && len(input) > max {
+		input = input.Prefix(max - 1)
+	}
+	p.beginInstance(input, nil)
+}
+
+// Begins the next instance with some input, resuming from the journaled messages it sent if any,
+// and replays queued messages for it.
+func (p *Participant) beginInstance(input ECChain, sent []*JournalEntry) {
+	power, err := p.powerTables.GetPowerTable(*input.Base(), p.config.PowerTableLookback)
+	if err != nil {
+		p.ntwk.Log("P%d: no power table for instance %d base %s: %s", p.id, p.nextInstance, input.Base(), err)
+		return
+	}
+	beacon, err := p.beacons.GetBeacon(*input.Base())
+	if err != nil {
+		p.ntwk.Log("P%d: no beacon for instance %d base %s: %s", p.id, p.nextInstance, input.Base(), err)
+		return
+	}
+	if p.journal != nil && sent == nil {
+		entry := &JournalEntry{Kind: JournalStart, Instance: p.nextInstance, Input: input}
+		if err := p.journal.Append(entry); err != nil {
+			p.fail(fmt.Errorf("not beginning instance %d which failed to journal: %w", p.nextInstance, err))
+			return
+		}
+	}
+	p.powerTable = power
+	p.powerTableInstance = p.nextInstance
+	p.granite = newInstance(p.config, p.guard, p.vrf, p.signer, p.timeouts, p.id, p.nextInstance, input, power, beacon,
+		p.chainStore, p.journal, &p.stats, p.reportEquivocation, p.fail)
+	p.nextInstance += 1
+	p.granite.Resume(sent)
+
+	// Replay messages queued for the new instance, and drop those for earlier instances.
+	p.mpool.Prune(p.granite.instanceID)
+	for _, msg := range p.mpool.Pop(p.granite.instanceID) {
+		if p.failure == nil && !p.granite.decided() {
+			p.granite.Receive(msg)
+		}
+	}
+}
+
+// Re-sends the DECIDE message for the last decided instance, at most once per rebroadcast delay.
+// This supersedes re-sending the last instance's COMMIT: a DECIDE carries the whole COMMIT quorum,
+// so a participant which missed the decision can adopt it directly.
+func (p *Participant) rebroadcastDecide() {
+	if p.decide == nil || p.config.RebroadcastDelay <= 0 || p.ntwk.Time() < p.decideTime+p.config.RebroadcastDelay {
+		return
+	}
+	p.decideTime = p.ntwk.Time()
+	p.guard.Broadcast(p.decide)
+}
+
+func (p *Participant) reportEquivocation(evidence *EquivocationEvidence) {
+	for _, listener := range p.equivocationListeners {
+		listener(evidence)
+	}
+}
+
+func (p *Participant) reportSelfEquivocation(err error) {
+	for _, listener := range p.selfEquivocationListeners {
+		listener(err)
+	}
+}
+
+// Halts the participant, notifying listeners.
+func (p *Participant) fail(err error) {
+	if p.failure != nil {
+		return
+	}
+	p.ntwk.Log("P%d: ‼️ halting: %s", p.id, err)
+	p.failure = err
+	for _, listener := range p.failureListeners {
+		listener(err)
+	}
+}
+
+// Checks whether a sender is in the latest known power table, or whether no power table is yet known.
+// Power tables change slowly, so a sender of messages for a later instance is very likely in the table
+// of the current instance if it is in that of the later one.
+func (p *Participant) isKnownSender(sender ActorID) bool {
+	if len(p.powerTable.Entries) == 0 {
+		return true
 	}
+	_, pubKey := p.powerTable.Get(sender)
+	return pubKey != nil
 }
 
 func (p *Participant) decided() bool {
-	return p.granite != nil && p.granite.phase == DECIDE
+	return p.failure == nil && p.granite != nil && p.granite.phase == DECIDE
 }
 
 func (p *Participant) Describe() string {
@@ -1,22 +1,85 @@
 package f3
 
-// A power table maps participant IDs to power values.
+import "math/big"
+
+// A power table entry: a participant's power and the public key with which it signs.
+type PowerEntry struct {
+	ID     ActorID
+	Power  *big.Int
+	PubKey PubKey
+}
+
+// A power table maps participant IDs to power values and public keys.
+// Entries are kept in order of insertion.
 type PowerTable struct {
-	Entries map[ActorID]uint
-	Total   uint
+	Entries []PowerEntry
+	// Index of each participant's entry in Entries.
+	Lookup map[ActorID]int
+	Total  *big.Int
 }
 
 func NewPowerTable() PowerTable {
 	return PowerTable{
-		Entries: map[ActorID]uint{},
-		Total:   0,
+		Entries: []PowerEntry{},
+		Lookup:  map[ActorID]int{},
+		Total:   new(big.Int),
 	}
 }
 
-func (p *PowerTable) Add(id ActorID, power uint) {
-	if p.Entries[id] != 0 {
+func (p *PowerTable) Add(id ActorID, power *big.Int, pubKey PubKey) {
+	if _, ok := p.Lookup[id]; ok {
 		panic("duplicate power entry")
 	}
-	p.Entries[id] = power
-	p.Total += power
+	if power.Sign() < 0 {
+		panic("negative power")
+	}
+	p.Lookup[id] = len(p.Entries)
+	p.Entries = append(p.Entries, PowerEntry{ID: id, Power: new(big.Int).Set(power), PubKey: pubKey})
+	// The total is replaced rather than updated in place, so copies of the table don't share it.
+	p.Total = new(big.Int).Add(p.Total, power)
+}
+
+// Returns the power and public key of a participant.
+// Returns zero power and a nil key for a participant not in the table.
+// The returned power must not be modified.
+func (p *PowerTable) Get(id ActorID) (*big.Int, PubKey) {
+	idx, ok := p.Lookup[id]
+	if !ok {
+		return new(big.Int), nil
+	}
+	return p.Entries[idx].Power, p.Entries[idx].PubKey
+}
+
+// Checks whether a participant is in the table.
+func (p *PowerTable) Has(id ActorID) bool {
+	_, ok := p.Lookup[id]
+	return ok
+}
+
+// Checks whether some power is a strong quorum of a total, i.e. strictly more than two thirds of it.
+// The comparison 3*power > 2*total is exact.
+func IsStrongQuorum(power, total *big.Int) bool {
+	lhs := new(big.Int).Mul(power, big.NewInt(3))
+	rhs := new(big.Int).Mul(total, big.NewInt(2))
+	return lhs.Cmp(rhs) > 0
+}
+
+// Scales some power as a fraction of a total into the range [0, scale], rounding down.
+// Returns zero if the total is zero, and clamps to scale if power exceeds the total.
+func ScalePower(power, total *big.Int, scale uint64) uint64 {
+	if total.Sign() <= 0 || power.Sign() <= 0 {
+		return 0
+	}
+	if power.Cmp(total) >= 0 {
+		return scale
+	}
+	scaled := new(big.Int).Mul(power, new(big.Int).SetUint64(scale))
+	scaled.Quo(scaled, total)
+	return scaled.Uint64()
+}
+
+// Provides the power table for each instance, from the chain state.
+type PowerTableProvider interface {
+	// Returns the power table from the state of the chain `lookback` epochs before an instance's base tipset.
+	GetPowerTable(base TipSet, lookback int) (PowerTable, error)
 }
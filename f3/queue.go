@@ -0,0 +1,166 @@
+package f3
+
+// A policy for making room for a new message in a full queue, selectable by GraniteConfig.QueueEviction.
+type EvictionPolicy string
+
+const (
+	// Evicts the queued message furthest in the future, or drops the new message if it is furthest.
+	EvictFurthest EvictionPolicy = "furthest"
+	// Drops the new message, retaining those already queued.
+	RejectNewest EvictionPolicy = "newest"
+)
+
+// Counts of messages dropped in order to bound the memory used by queued messages.
+type QueueStats struct {
+	// Messages for an instance or round too far beyond the current one.
+	DroppedFuture int
+	// Messages for a later instance from a sender not in the latest known power table.
+	DroppedUnknownSender int
+	// Messages dropped because their sender had too many messages queued.
+	DroppedSenderLimit int
+	// Messages dropped because a queue was full.
+	DroppedTotalLimit int
+}
+
+// Bounds on the number of messages held in a queue, from each sender and in total.
+type queueBounds struct {
+	maxPerSender int
+	maxTotal     int
+	eviction     EvictionPolicy
+	stats        *QueueStats
+}
+
+func newQueueBounds(config GraniteConfig, stats *QueueStats) queueBounds {
+	return queueBounds{
+		maxPerSender: config.MaxQueuedPerSender,
+		maxTotal:     config.MaxQueued,
+		eviction:     config.QueueEviction,
+		stats:        stats,
+	}
+}
+
+// Chooses a message to drop in order to admit a new message to a queue.
+// Returns nil if there is room for the new message, the new message itself if it is to be dropped,
+// or a queued message to evict in its place.
+// The queued messages are provided lazily, since they are needed only when the queue is full.
+func (b queueBounds) victim(msg *GMessage, senderCount int, total int, queued func() []*GMessage) *GMessage {
+	if b.maxPerSender > 0 && senderCount >= b.maxPerSender {
+		b.stats.DroppedSenderLimit += 1
+		var fromSender []*GMessage
+		for _, q := range queued() {
+			if q.Sender == msg.Sender {
+				fromSender = append(fromSender, q)
+			}
+		}
+		return b.choose(msg, fromSender)
+	}
+	if b.maxTotal > 0 && total >= b.maxTotal {
+		b.stats.DroppedTotalLimit += 1
+		return b.choose(msg, queued())
+	}
+	return nil
+}
+
+// Chooses between a new message and some queued messages according to the eviction policy.
+func (b queueBounds) choose(msg *GMessage, queued []*GMessage) *GMessage {
+	if b.eviction == RejectNewest {
+		return msg
+	}
+	// The new message is dropped in preference to a queued message equally far in the future.
+	furthest := msg
+	for _, q := range queued {
+		if isFurther(q, furthest) {
+			furthest = q
+		}
+	}
+	return furthest
+}
+
+// Checks whether a message is for a later instance, round, or phase than another.
+func isFurther(a, b *GMessage) bool {
+	if a.Instance != b.Instance {
+		return a.Instance > b.Instance
+	}
+	if a.Round != b.Round {
+		return a.Round > b.Round
+	}
+	return phaseOrder(a.Step) > phaseOrder(b.Step)
+}
+
+func phaseOrder(step string) int {
+	switch step {
+	case QUALITY:
+		return 0
+	case CONVERGE:
+		return 1
+	case PREPARE:
+		return 2
+	case COMMIT:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Messages held for instances after the current one, bounded in number from each sender and in total.
+type messagePool struct {
+	bounds queueBounds
+	// Queued messages, in order of receipt.
+	msgs []*GMessage
+	// Number of queued messages from each sender.
+	senders map[ActorID]int
+}
+
+func newMessagePool(bounds queueBounds) *messagePool {
+	return &messagePool{bounds: bounds, senders: map[ActorID]int{}}
+}
+
+// Queues a message, dropping either it or a queued message if the pool is full.
+func (m *messagePool) Add(msg *GMessage) {
+	victim := m.bounds.victim(msg, m.senders[msg.Sender], len(m.msgs), func() []*GMessage { return m.msgs })
+	if victim == msg {
+		return
+	} else if victim != nil {
+		m.removeWhere(func(q *GMessage) bool { return q == victim })
+	}
+	m.msgs = append(m.msgs, msg)
+	m.senders[msg.Sender] += 1
+}
+
+// Dequeues the messages for an instance, in order of receipt.
+func (m *messagePool) Pop(instance int) []*GMessage {
+	return m.removeWhere(func(q *GMessage) bool { return q.Instance == instance })
+}
+
+// Drops messages for all instances before some instance.
+func (m *messagePool) Prune(before int) {
+	m.removeWhere(func(q *GMessage) bool { return q.Instance < before })
+}
+
+func (m *messagePool) Len() int {
+	return len(m.msgs)
+}
+
+// Removes and returns the messages matching a predicate.
+func (m *messagePool) removeWhere(pred func(msg *GMessage) bool) []*GMessage {
+	var removed []*GMessage
+	n := 0
+	for _, msg := range m.msgs {
+		if pred(msg) {
+			removed = append(removed, msg)
+			m.senders[msg.Sender] -= 1
+			if m.senders[msg.Sender] == 0 {
+				delete(m.senders, msg.Sender)
+			}
+		} else {
+			m.msgs[n] = msg
+			n += 1
+		}
+	}
+	// Clear the tail so removed messages can be collected.
+	for j := n; j < len(m.msgs); j++ {
+		m.msgs[j] = nil
+	}
+	m.msgs = m.msgs[:n]
+	return removed
+}
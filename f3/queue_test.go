@@ -0,0 +1,110 @@
+package f3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessagePoolBounds(t *testing.T) {
+	msg := func(sender ActorID, instance int) *GMessage {
+		return &GMessage{Sender: sender, Instance: instance, Step: QUALITY}
+	}
+
+	t.Run("unbounded", func(t *testing.T) {
+		stats := &QueueStats{}
+		pool := newMessagePool(newQueueBounds(GraniteConfig{}, stats))
+		for n := 0; n < 100; n++ {
+			pool.Add(msg(1, n))
+		}
+		require.Equal(t, 100, pool.Len())
+		require.Equal(t, QueueStats{}, *stats)
+	})
+
+	t.Run("sender limit evicts furthest", func(t *testing.T) {
+		stats := &QueueStats{}
+		pool := newMessagePool(newQueueBounds(GraniteConfig{MaxQueuedPerSender: 2}, stats))
+		far := msg(1, 9)
+		pool.Add(far)
+		pool.Add(msg(1, 3))
+		pool.Add(msg(1, 5))
+		// Other senders are unaffected.
+		pool.Add(msg(2, 9))
+		require.Equal(t, 3, pool.Len())
+		require.Equal(t, 1, stats.DroppedSenderLimit)
+		require.NotContains(t, pool.msgs, far)
+
+		// A new message further than those queued is itself dropped.
+		pool.Add(msg(1, 7))
+		require.Equal(t, 3, pool.Len())
+		require.Equal(t, 2, stats.DroppedSenderLimit)
+		require.Len(t, pool.Pop(3), 1)
+		require.Len(t, pool.Pop(5), 1)
+		require.Empty(t, pool.Pop(7))
+	})
+
+	t.Run("total limit evicts furthest", func(t *testing.T) {
+		stats := &QueueStats{}
+		pool := newMessagePool(newQueueBounds(GraniteConfig{MaxQueued: 3}, stats))
+		pool.Add(msg(1, 9))
+		pool.Add(msg(2, 2))
+		pool.Add(msg(3, 4))
+		pool.Add(msg(4, 1))
+		require.Equal(t, 3, pool.Len())
+		require.Equal(t, 1, stats.DroppedTotalLimit)
+		pool.Prune(3)
+		require.Equal(t, 1, pool.Len())
+		require.Equal(t, 4, pool.Pop(4)[0].Instance)
+		require.Empty(t, pool.senders)
+	})
+
+	t.Run("reject newest", func(t *testing.T) {
+		stats := &QueueStats{}
+		pool := newMessagePool(newQueueBounds(GraniteConfig{MaxQueued: 2, QueueEviction: RejectNewest}, stats))
+		pool.Add(msg(1, 9))
+		pool.Add(msg(2, 8))
+		pool.Add(msg(3, 1))
+		require.Equal(t, 2, pool.Len())
+		require.Equal(t, 1, stats.DroppedTotalLimit)
+		require.Empty(t, pool.Pop(1))
+	})
+}
+
+func TestPendingQueueBounds(t *testing.T) {
+	msg := func(sender ActorID, round int, step string) *GMessage {
+		return &GMessage{Sender: sender, Round: round, Step: step}
+	}
+	all := func(*GMessage) bool { return true }
+
+	stats := &QueueStats{}
+	q := newPendingQueue(newQueueBounds(GraniteConfig{MaxQueuedPerSender: 2, MaxQueued: 3}, stats))
+	q.Add(msg(1, 1, COMMIT))
+	q.Add(msg(1, 1, CONVERGE))
+	// Evicts the sender's COMMIT, which is later in the round.
+	q.Add(msg(1, 0, COMMIT))
+	require.Equal(t, 1, stats.DroppedSenderLimit)
+	require.Equal(t, 2, q.Len())
+	require.Empty(t, q.PopWhere(1, COMMIT, all))
+
+	q.Add(msg(2, 2, PREPARE))
+	// Evicts the other sender's message in the furthest round.
+	q.Add(msg(3, 0, COMMIT))
+	require.Equal(t, 1, stats.DroppedTotalLimit)
+	require.Equal(t, 3, q.Len())
+	require.Empty(t, q.PopWhere(2, PREPARE, all))
+
+	require.Len(t, q.PopWhere(0, COMMIT, all), 2)
+	require.Len(t, q.PopWhere(1, CONVERGE, all), 1)
+	require.Zero(t, q.Len())
+	require.Empty(t, q.senders)
+}
+
+func TestQueueBoundDefaults(t *testing.T) {
+	config := GraniteConfig{MaxQueued: -1, MaxFutureRounds: 3}.withDefaults()
+	require.Equal(t, DefaultMaxFutureInstances, config.MaxFutureInstances)
+	require.Equal(t, 3, config.MaxFutureRounds)
+	require.Equal(t, DefaultMaxQueuedPerSender, config.MaxQueuedPerSender)
+	// A negative bound means unbounded.
+	require.Equal(t, -1, config.MaxQueued)
+	require.Nil(t, newQueueBounds(config, &QueueStats{}).victim(&GMessage{}, 0, 1<<20, nil))
+}
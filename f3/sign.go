@@ -0,0 +1,34 @@
+package f3
+
+// A participant's public signing key.
+type PubKey []byte
+
+// Signs message payloads.
+type Signer interface {
+	// Signs a payload with the private key corresponding to a public key.
+	// The signer must hold the private key for the public key.
+	Sign(sender PubKey, msg []byte) []byte
+}
+
+// Verifies message signatures.
+type Verifier interface {
+	// Checks whether a signature over a payload was produced with the private key corresponding to a public key.
+	Verify(pubKey PubKey, msg []byte, sig []byte) bool
+}
+
+// Aggregates signatures over a common payload into a single signature.
+type Aggregator interface {
+	// Aggregates signatures produced by the holders of some public keys over the same payload.
+	// The public keys and signatures must be in corresponding order.
+	Aggregate(pubKeys []PubKey, sigs [][]byte) ([]byte, error)
+	// Checks whether an aggregate signature over a payload was produced by the holders of some public keys.
+	// The public keys must be in the order in which they were aggregated.
+	VerifyAggregate(payload []byte, aggSig []byte, pubKeys []PubKey) bool
+}
+
+// A signer that can also verify and aggregate signatures.
+type SignerVerifier interface {
+	Signer
+	Verifier
+	Aggregator
+}
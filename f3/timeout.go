@@ -0,0 +1,114 @@
+package f3
+
+import (
+	"fmt"
+	"math"
+)
+
+// The name of a timeout policy, selectable by GraniteConfig.Timeout.
+type TimeoutKind string
+
+const (
+	TimeoutLinear      TimeoutKind = "linear"
+	TimeoutExponential TimeoutKind = "exponential"
+	TimeoutAdaptive    TimeoutKind = "adaptive"
+)
+
+// Determines how long each Granite phase waits for messages before timing out.
+// A policy is retained by a participant across instances, so may learn from earlier ones.
+type TimeoutPolicy interface {
+	// Returns the timeout duration for a phase in some round of an instance.
+	Timeout(instance int, round int, phase string) float64
+	// Receives the time elapsed from the start of a phase until this participant observed a strong quorum.
+	ObserveQuorum(instance int, round int, phase string, elapsed float64)
+}
+
+// Returns the timeout policy selected by a configuration.
+// An empty policy name selects the linear policy.
+// Returns an error wrapping ErrInvalidConfig if the policy is unknown.
+func NewTimeoutPolicy(config GraniteConfig) (TimeoutPolicy, error) {
+	switch config.Timeout {
+	case "", TimeoutLinear:
+		return &LinearTimeout{Delta: config.Delta, DeltaRate: config.DeltaRate, Max: config.DeltaMax}, nil
+	case TimeoutExponential:
+		return &ExponentialTimeout{Delta: config.Delta, Backoff: config.DeltaBackoff, Max: config.DeltaMax}, nil
+	case TimeoutAdaptive:
+		return NewAdaptiveTimeout(config.Delta, config.DeltaRate, config.DeltaMin, config.DeltaMax), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown timeout policy %q", ErrInvalidConfig, config.Timeout)
+	}
+}
+
+// A timeout that increases by a fixed amount each round.
+type LinearTimeout struct {
+	// Timeout in the first round.
+	Delta float64
+	// Increase in the timeout with each round.
+	DeltaRate float64
+	// Maximum timeout. Zero means unbounded.
+	Max float64
+}
+
+func (t *LinearTimeout) Timeout(_ int, round int, _ string) float64 {
+	return capTimeout(t.Delta+float64(round)*t.DeltaRate, t.Max)
+}
+
+func (t *LinearTimeout) ObserveQuorum(int, int, string, float64) {}
+
+// A timeout that increases by a factor each round.
+type ExponentialTimeout struct {
+	// Timeout in the first round.
+	Delta float64
+	// Factor by which the timeout increases with each round. Values less than one are treated as one.
+	Backoff float64
+	// Maximum timeout. Zero means unbounded.
+	Max float64
+}
+
+func (t *ExponentialTimeout) Timeout(_ int, round int, _ string) float64 {
+	return capTimeout(t.Delta*math.Pow(math.Max(t.Backoff, 1), float64(round)), t.Max)
+}
+
+func (t *ExponentialTimeout) ObserveQuorum(int, int, string, float64) {}
+
+// Weight of each new observation in the adaptive timeout's moving average.
+const adaptiveSmoothing = 0.25
+
+// Multiple of the estimated time to quorum used as the adaptive timeout in the first round.
+const adaptiveMargin = 2.0
+
+// A timeout learned from the time taken to observe strong quorums in earlier phases and instances.
+// The first round's timeout is a multiple of a moving average of observed times to quorum,
+// starting from an initial delta, and increases by a fixed amount each subsequent round.
+type AdaptiveTimeout struct {
+	// Increase in the timeout with each round.
+	deltaRate float64
+	// Minimum timeout in the first round.
+	min float64
+	// Maximum timeout. Zero means unbounded.
+	max float64
+	// Moving average of the time taken to observe a strong quorum.
+	estimate float64
+}
+
+// Creates an adaptive timeout whose first round timeout begins at delta,
+// and is subsequently learned to be no less than min.
+func NewAdaptiveTimeout(delta, deltaRate, min, max float64) *AdaptiveTimeout {
+	return &AdaptiveTimeout{deltaRate: deltaRate, min: min, max: max, estimate: delta / adaptiveMargin}
+}
+
+func (t *AdaptiveTimeout) Timeout(_ int, round int, _ string) float64 {
+	delta := math.Max(t.estimate*adaptiveMargin, t.min)
+	return capTimeout(delta+float64(round)*t.deltaRate, t.max)
+}
+
+func (t *AdaptiveTimeout) ObserveQuorum(_ int, _ int, _ string, elapsed float64) {
+	t.estimate += adaptiveSmoothing * (elapsed - t.estimate)
+}
+
+func capTimeout(timeout, max float64) float64 {
+	if max > 0 {
+		return math.Min(timeout, max)
+	}
+	return timeout
+}
@@ -0,0 +1,70 @@
+package f3_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+func newTimeoutPolicy(t *testing.T, config f3.GraniteConfig) f3.TimeoutPolicy {
+	policy, err := f3.NewTimeoutPolicy(config)
+	require.NoError(t, err)
+	return policy
+}
+
+func TestLinearTimeout(t *testing.T) {
+	policy := newTimeoutPolicy(t, f3.GraniteConfig{Delta: 1, DeltaRate: 0.5, DeltaMax: 2})
+	require.Equal(t, 1.0, policy.Timeout(0, 0, f3.QUALITY))
+	require.Equal(t, 2.0, policy.Timeout(0, 2, f3.PREPARE))
+	require.Equal(t, 2.0, policy.Timeout(0, 5, f3.PREPARE))
+	// Observations are ignored.
+	policy.ObserveQuorum(0, 0, f3.QUALITY, 0.1)
+	require.Equal(t, 1.0, policy.Timeout(1, 0, f3.QUALITY))
+}
+
+func TestExponentialTimeout(t *testing.T) {
+	policy := newTimeoutPolicy(t, f3.GraniteConfig{Timeout: f3.TimeoutExponential, Delta: 1, DeltaBackoff: 2})
+	require.Equal(t, 1.0, policy.Timeout(0, 0, f3.QUALITY))
+	require.Equal(t, 2.0, policy.Timeout(0, 1, f3.CONVERGE))
+	require.Equal(t, 8.0, policy.Timeout(0, 3, f3.CONVERGE))
+
+	capped := newTimeoutPolicy(t, f3.GraniteConfig{Timeout: f3.TimeoutExponential, Delta: 1, DeltaBackoff: 2,
+		DeltaMax: 5})
+	require.Equal(t, 5.0, capped.Timeout(0, 3, f3.CONVERGE))
+
+	// A backoff less than one does not shrink the timeout.
+	flat := newTimeoutPolicy(t, f3.GraniteConfig{Timeout: f3.TimeoutExponential, Delta: 1, DeltaBackoff: 0.5})
+	require.Equal(t, 1.0, flat.Timeout(0, 3, f3.CONVERGE))
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	policy := newTimeoutPolicy(t, f3.GraniteConfig{Timeout: f3.TimeoutAdaptive, Delta: 4, DeltaRate: 1,
+		DeltaMin: 0.5, DeltaMax: 10})
+	require.Equal(t, 4.0, policy.Timeout(0, 0, f3.QUALITY))
+	require.Equal(t, 6.0, policy.Timeout(0, 2, f3.PREPARE))
+
+	// Fast quorums shrink the timeout, down to the minimum.
+	for n := 0; n < 50; n++ {
+		policy.ObserveQuorum(n, 0, f3.PREPARE, 0.1)
+	}
+	require.Equal(t, 0.5, policy.Timeout(50, 0, f3.QUALITY))
+	require.Equal(t, 1.5, policy.Timeout(50, 1, f3.QUALITY))
+
+	// Slow quorums grow the timeout, up to the maximum.
+	policy.ObserveQuorum(50, 0, f3.PREPARE, 3)
+	grown := policy.Timeout(51, 0, f3.QUALITY)
+	require.Greater(t, grown, 0.5)
+	require.Less(t, grown, 6.0)
+	for n := 0; n < 50; n++ {
+		policy.ObserveQuorum(n, 0, f3.PREPARE, 20)
+	}
+	require.Equal(t, 10.0, policy.Timeout(100, 0, f3.QUALITY))
+}
+
+func TestUnknownTimeoutPolicy(t *testing.T) {
+	config := f3.GraniteConfig{Timeout: "unknown"}
+	_, err := f3.NewTimeoutPolicy(config)
+	require.ErrorIs(t, err, f3.ErrInvalidConfig)
+	require.ErrorIs(t, config.Validate(), f3.ErrInvalidConfig)
+}
@@ -1,5 +1,3 @@
 package f3
 
 type ActorID uint64
-
-type CID = string
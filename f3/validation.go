@@ -0,0 +1,120 @@
+package f3
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by validation of chains and messages.
+// A returned error wraps one of these, with detail about the invalid field.
+var (
+	// A chain's tipsets are not in strictly increasing epoch order.
+	ErrEpochsNotIncreasing = errors.New("epochs not increasing")
+	// A chain's tipsets decrease in weight.
+	ErrWeightDecreasing = errors.New("weight decreasing")
+	// A chain has more tipsets than the configured maximum.
+	ErrChainTooLong = errors.New("chain too long")
+	// A message's step is not a Granite step.
+	ErrUnknownStep = errors.New("unknown step")
+	// A message's instance or round is out of range for its step.
+	ErrInvalidRound = errors.New("invalid instance or round")
+	// A message's value is bottom in a step which doesn't allow it, or is a chain with an unexpected base.
+	ErrInvalidValue = errors.New("invalid value")
+	// A message carries a ticket in a step other than CONVERGE, or lacks one in CONVERGE.
+	ErrInvalidTicket = errors.New("invalid ticket")
+	// A message carries a justification in a step which is never justified, or a malformed one.
+	ErrInvalidJustification = errors.New("invalid justification")
+	// A message's sender is not in the instance's power table.
+	ErrUnknownSender = errors.New("unknown sender")
+	// A message's signature does not verify against its sender's key.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// A configuration has an unknown policy or an out of range value.
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// Checks that a configuration selects known policies and allows chains longer than the base alone.
+// Returns an error wrapping ErrInvalidConfig if not.
+func (c *GraniteConfig) Validate() error {
+	if _, err := NewTimeoutPolicy(*c); err != nil {
+		return err
+	}
+	// An instance's input is truncated to the maximum length, so must be able to extend its base.
+	if c.MaxChainLength < 0 || c.MaxChainLength == 1 {
+		return fmt.Errorf("%w: maximum chain length %d is neither zero nor at least 2", ErrInvalidConfig,
+			c.MaxChainLength)
+	}
+	switch c.QueueEviction {
+	case "", EvictFurthest, RejectNewest:
+	default:
+		return fmt.Errorf("%w: unknown queue eviction policy %q", ErrInvalidConfig, c.QueueEviction)
+	}
+	return nil
+}
+
+// Checks that a chain is well formed: tipset epochs strictly increase, weights do not decrease,
+// and the chain has at most maxLength tipsets, including the base.
+// A zero maxLength means unbounded.
+// The zero value (bottom) is valid.
+func (c ECChain) Validate(maxLength int) error {
+	if maxLength > 0 && len(c) > maxLength {
+		return fmt.Errorf("%w: %d tipsets exceeds maximum %d", ErrChainTooLong, len(c), maxLength)
+	}
+	for j := 1; j < len(c); j++ {
+		if c[j].Epoch <= c[j-1].Epoch {
+			return fmt.Errorf("%w: epoch %d at index %d follows %d", ErrEpochsNotIncreasing, c[j].Epoch, j,
+				c[j-1].Epoch)
+		}
+		if c[j].Weight < c[j-1].Weight {
+			return fmt.Errorf("%w: weight %d at index %d follows %d", ErrWeightDecreasing, c[j].Weight, j,
+				c[j-1].Weight)
+		}
+	}
+	return nil
+}
+
+// Checks that a message is well formed, independent of the state of any instance:
+// the step is known, the instance and round are in range for the step, values are well formed chains
+// of at most maxChainLength tipsets and are not bottom where the step forbids it,
+// and tickets and justifications are present only in the steps which use them.
+// Signatures, tickets and justifications are not verified.
+func (m *GMessage) Validate(maxChainLength int) error {
+	if m.Instance < 0 || m.Round < 0 {
+		return fmt.Errorf("%w: instance %d round %d", ErrInvalidRound, m.Instance, m.Round)
+	}
+	switch m.Step {
+	case QUALITY:
+		if m.Round != 0 {
+			return fmt.Errorf("%w: QUALITY in round %d", ErrInvalidRound, m.Round)
+		}
+	case CONVERGE:
+		if m.Round == 0 {
+			return fmt.Errorf("%w: CONVERGE in round 0", ErrInvalidRound)
+		}
+	case PREPARE, COMMIT, DECIDE:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownStep, m.Step)
+	}
+	if err := m.Value.Validate(maxChainLength); err != nil {
+		return fmt.Errorf("value: %w", err)
+	}
+	if m.Value.IsZero() && (m.Step == QUALITY || m.Step == CONVERGE || m.Step == DECIDE) {
+		return fmt.Errorf("%w: bottom in %s", ErrInvalidValue, m.Step)
+	}
+	if (m.Step == CONVERGE) != (len(m.Ticket) > 0) {
+		return fmt.Errorf("%w: %d byte ticket in %s", ErrInvalidTicket, len(m.Ticket), m.Step)
+	}
+	if j := m.Justification; j != nil {
+		switch m.Step {
+		case CONVERGE, COMMIT, DECIDE:
+		default:
+			return fmt.Errorf("%w: justification in %s", ErrInvalidJustification, m.Step)
+		}
+		if j.Round < 0 || (j.Step != PREPARE && j.Step != COMMIT) {
+			return fmt.Errorf("%w: %s in round %d", ErrInvalidJustification, j.Step, j.Round)
+		}
+		if err := j.Value.Validate(maxChainLength); err != nil {
+			return fmt.Errorf("%w: value: %w", ErrInvalidJustification, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package f3_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainValidate(t *testing.T) {
+	base := f3.NewTipSet(10, f3.CIDOf([]byte("base")), 5)
+	a := f3.NewTipSet(11, f3.CIDOf([]byte("a")), 6)
+	b := f3.NewTipSet(13, f3.CIDOf([]byte("b")), 6)
+
+	require.NoError(t, f3.ECChain{}.Validate(0))
+	require.NoError(t, f3.ECChain{base, a, b}.Validate(0))
+	require.NoError(t, f3.ECChain{base, a, b}.Validate(3))
+	require.ErrorIs(t, f3.ECChain{base, a, b}.Validate(2), f3.ErrChainTooLong)
+	require.ErrorIs(t, f3.ECChain{base, b, a}.Validate(0), f3.ErrEpochsNotIncreasing)
+	require.ErrorIs(t, f3.ECChain{base, a, a}.Validate(0), f3.ErrEpochsNotIncreasing)
+	lighter := f3.NewTipSet(12, f3.CIDOf([]byte("c")), 4)
+	require.ErrorIs(t, f3.ECChain{base, a, lighter}.Validate(0), f3.ErrWeightDecreasing)
+}
+
+func TestMessageValidate(t *testing.T) {
+	base := f3.NewTipSet(10, f3.CIDOf([]byte("base")), 5)
+	chain := f3.NewChain(base, f3.NewTipSet(11, f3.CIDOf([]byte("a")), 6))
+	unordered := f3.ECChain{base, f3.NewTipSet(9, f3.CIDOf([]byte("a")), 6)}
+	justification := &f3.Justification{Round: 1, Step: f3.PREPARE, Value: chain}
+
+	for _, test := range []struct {
+		name     string
+		msg      f3.GMessage
+		expected error
+	}{
+		{"quality", f3.GMessage{Step: f3.QUALITY, Value: chain}, nil},
+		{"converge", f3.GMessage{Round: 2, Step: f3.CONVERGE, Value: chain, Ticket: []byte("t"),
+			Justification: justification}, nil},
+		{"prepare bottom", f3.GMessage{Round: 3, Step: f3.PREPARE}, nil},
+		{"commit", f3.GMessage{Round: 1, Step: f3.COMMIT, Value: chain, Justification: justification}, nil},
+		{"decide", f3.GMessage{Round: 1, Step: f3.DECIDE, Value: chain,
+			Justification: &f3.Justification{Round: 1, Step: f3.COMMIT, Value: chain}}, nil},
+		{"unknown step", f3.GMessage{Step: "VOTE", Value: chain}, f3.ErrUnknownStep},
+		{"negative instance", f3.GMessage{Instance: -1, Step: f3.PREPARE, Value: chain}, f3.ErrInvalidRound},
+		{"negative round", f3.GMessage{Round: -1, Step: f3.PREPARE, Value: chain}, f3.ErrInvalidRound},
+		{"quality after round 0", f3.GMessage{Round: 1, Step: f3.QUALITY, Value: chain}, f3.ErrInvalidRound},
+		{"converge in round 0", f3.GMessage{Step: f3.CONVERGE, Value: chain, Ticket: []byte("t")},
+			f3.ErrInvalidRound},
+		{"quality bottom", f3.GMessage{Step: f3.QUALITY}, f3.ErrInvalidValue},
+		{"converge bottom", f3.GMessage{Round: 1, Step: f3.CONVERGE, Ticket: []byte("t")}, f3.ErrInvalidValue},
+		{"unordered value", f3.GMessage{Step: f3.QUALITY, Value: unordered}, f3.ErrEpochsNotIncreasing},
+		{"converge without ticket", f3.GMessage{Round: 1, Step: f3.CONVERGE, Value: chain}, f3.ErrInvalidTicket},
+		{"prepare with ticket", f3.GMessage{Step: f3.PREPARE, Value: chain, Ticket: []byte("t")},
+			f3.ErrInvalidTicket},
+		{"prepare with justification", f3.GMessage{Step: f3.PREPARE, Value: chain, Justification: justification},
+			f3.ErrInvalidJustification},
+		{"justification of unknown step", f3.GMessage{Step: f3.COMMIT, Value: chain,
+			Justification: &f3.Justification{Step: f3.QUALITY, Value: chain}}, f3.ErrInvalidJustification},
+		{"justification with unordered value", f3.GMessage{Step: f3.COMMIT, Value: chain,
+			Justification: &f3.Justification{Step: f3.PREPARE, Value: unordered}}, f3.ErrEpochsNotIncreasing},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.msg.Validate(0)
+			if test.expected == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, test.expected)
+			}
+		})
+	}
+
+	long := f3.GMessage{Step: f3.QUALITY, Value: chain}
+	require.NoError(t, long.Validate(2))
+	require.ErrorIs(t, long.Validate(1), f3.ErrChainTooLong)
+}
+
+func TestConfigValidate(t *testing.T) {
+	require.NoError(t, (&f3.GraniteConfig{}).Validate())
+	require.NoError(t, (&f3.GraniteConfig{Timeout: f3.TimeoutAdaptive, QueueEviction: f3.RejectNewest}).Validate())
+	require.ErrorIs(t, (&f3.GraniteConfig{Timeout: "unknown"}).Validate(), f3.ErrInvalidConfig)
+	require.ErrorIs(t, (&f3.GraniteConfig{QueueEviction: "oldest"}).Validate(), f3.ErrInvalidConfig)
+	require.NoError(t, (&f3.GraniteConfig{MaxChainLength: 2}).Validate())
+	require.ErrorIs(t, (&f3.GraniteConfig{MaxChainLength: 1}).Validate(), f3.ErrInvalidConfig)
+	require.ErrorIs(t, (&f3.GraniteConfig{MaxChainLength: -1}).Validate(), f3.ErrInvalidConfig)
+}
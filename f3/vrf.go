@@ -2,7 +2,10 @@ package f3
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"math/bits"
 )
 
 // A ticket is a signature over some common payload.
@@ -12,17 +15,110 @@ func (t Ticket) Compare(other Ticket) int {
 	return bytes.Compare(t, other)
 }
 
+// Scale to which sender power is reduced, as a fraction of the total, for ranking tickets.
+const TicketPowerScale = 1 << 32
+
+// Number of fractional bits in the fixed-point logarithm of a ticket rank.
+const ticketRankFractionBits = 56
+
+// The rank of a ticket from a sender with some power, as the exact ratio of two integers,
+// so that ranks compare identically on every platform.
+type TicketRank struct {
+	// Negative base-2 logarithm of a uniform value in (0, 1], in fixed point with ticketRankFractionBits.
+	negLog uint64
+	// The sender's scaled power; zero for infinite rank.
+	power uint64
+}
+
+// Computes the rank of a ticket from a sender with some power, scaled by TicketPowerScale.
+// The lowest rank wins. The rank is an exponentially distributed variable with rate proportional to power,
+// derived from a uniform value digested from the ticket, so that the probability of a sender's ticket
+// having the lowest rank is proportional to the sender's power.
+// The logarithm is computed with integer arithmetic only, so ranks don't depend on floating point behaviour.
+// A sender with zero scaled power has infinite rank.
+func ComputeTicketRank(ticket Ticket, scaledPower uint64) TicketRank {
+	digest := sha256.Sum256(ticket)
+	// The top 63 bits of the digest, as a fraction m / 2^63 in (0, 1].
+	m := binary.BigEndian.Uint64(digest[:8])>>1 + 1
+	return TicketRank{negLog: 63<<ticketRankFractionBits - log2Fixed(m), power: scaledPower}
+}
+
+// Compares two ranks, returning -1, 0 or 1 as this rank is lower than, equal to or higher than the other.
+func (r TicketRank) Compare(other TicketRank) int {
+	// Infinite ranks are equal, and higher than any finite rank.
+	switch {
+	case r.power == 0 && other.power == 0:
+		return 0
+	case r.power == 0:
+		return 1
+	case other.power == 0:
+		return -1
+	}
+	// Compares r.negLog / r.power with other.negLog / other.power by cross-multiplying to 128 bits.
+	hi1, lo1 := bits.Mul64(r.negLog, other.power)
+	hi2, lo2 := bits.Mul64(other.negLog, r.power)
+	switch {
+	case hi1 < hi2 || (hi1 == hi2 && lo1 < lo2):
+		return -1
+	case hi1 > hi2 || (hi1 == hi2 && lo1 > lo2):
+		return 1
+	}
+	return 0
+}
+
+// Computes log2(m) for m > 0 in fixed point with ticketRankFractionBits, truncated.
+// The fraction bits are found by repeated squaring of the mantissa.
+func log2Fixed(m uint64) uint64 {
+	k := bits.Len64(m) - 1
+	result := uint64(k) << ticketRankFractionBits
+	// Mantissa m / 2^k in [1, 2), in fixed point with 62 fractional bits.
+	y := m << (63 - k) >> 1
+	for bit := uint64(1) << (ticketRankFractionBits - 1); bit > 0; bit >>= 1 {
+		// Square, keeping 62 fractional bits. The square is below 4, so fits in 64 bits.
+		hi, lo := bits.Mul64(y, y)
+		y = hi<<2 | lo>>62
+		if y >= 1<<63 {
+			result |= bit
+			y >>= 1
+		}
+	}
+	return result
+}
+
 // Computes VRF tickets for use in CONVERGE phase.
 // A VRF ticket is produced by signing a payload which digests a beacon randomness value and
 // the instance and round numbers.
+// The signer is identified by its public key from the instance's power table.
 type VRFTicketSource interface {
-	MakeTicket(beacon []byte, instance int, round int, signer ActorID) Ticket
+	MakeTicket(beacon []byte, instance int, round int, signer PubKey) Ticket
 }
 
 type VRFTicketVerifier interface {
-	VerifyTicket(beacon []byte, instance int, round int, signer ActorID, ticket Ticket) bool
+	VerifyTicket(beacon []byte, instance int, round int, signer PubKey, ticket Ticket) bool
 }
 
+// A VRF which produces a ticket by signing the VRF payload with the participant's key,
+// and verifies a ticket as a signature by the public key from the power table.
+// The signature scheme must be unique (as BLS is), so that each participant has exactly one
+// valid ticket for each beacon, instance and round, which no other party can compute.
+type SignatureVRF struct {
+	signer   Signer
+	verifier Verifier
+}
+
+func NewSignatureVRF(signer Signer, verifier Verifier) *SignatureVRF {
+	return &SignatureVRF{signer: signer, verifier: verifier}
+}
+
+func (v *SignatureVRF) MakeTicket(beacon []byte, instance int, round int, signer PubKey) Ticket {
+	return v.signer.Sign(signer, VRFPayload(beacon, instance, round))
+}
+
+func (v *SignatureVRF) VerifyTicket(beacon []byte, instance int, round int, signer PubKey, ticket Ticket) bool {
+	return v.verifier.Verify(signer, VRFPayload(beacon, instance, round), ticket)
+}
+
+// A VRF for simulations, whose tickets anyone can compute.
 type FakeVRF struct {
 }
 
@@ -30,10 +126,16 @@ func NewFakeVRF() *FakeVRF {
 	return &FakeVRF{}
 }
 
-func (f *FakeVRF) MakeTicket(beacon []byte, instance int, round int, signer ActorID) Ticket {
-	return []byte(fmt.Sprintf("FakeTicket(%x, %d, %d, %d)", beacon, instance, round, signer))
+func (f *FakeVRF) MakeTicket(beacon []byte, instance int, round int, signer PubKey) Ticket {
+	return []byte(fmt.Sprintf("FakeTicket(%x, %d, %d, %x)", beacon, instance, round, signer))
+}
+
+func (f *FakeVRF) VerifyTicket(beacon []byte, instance int, round int, signer PubKey, ticket Ticket) bool {
+	return string(ticket) == fmt.Sprintf("FakeTicket(%x, %d, %d, %x)", beacon, instance, round, signer)
 }
 
-func (f *FakeVRF) VerifyTicket(beacon []byte, instance int, round int, signer ActorID, ticket Ticket) bool {
-	return string(ticket) == fmt.Sprintf("FakeTicket(%x, %d, %d, %d)", beacon, instance, round, signer)
+// Provides the beacon randomness for each instance, from the chain state.
+type BeaconProvider interface {
+	// Returns the beacon value from an instance's base tipset.
+	GetBeacon(base TipSet) ([]byte, error)
 }
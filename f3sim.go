@@ -3,6 +3,8 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+
 	"github.com/filecoin-project/go-f3/f3"
 	"github.com/filecoin-project/go-f3/sim"
 	"time"
@@ -18,9 +20,20 @@ func main() {
 
 	graniteDelta := flag.Float64("granite-delta", 6.000, "granite delta parameter")
 	graniteDeltaRate := flag.Float64("granite-delta-rate", 2.000, "change in delta for each round")
+	graniteTimeout := flag.String("granite-timeout", string(f3.TimeoutLinear), "timeout policy: linear, exponential or adaptive")
 
 	flag.Parse()
 
+	graniteConfig := f3.GraniteConfig{
+		Delta:     *graniteDelta,
+		DeltaRate: *graniteDeltaRate,
+		Timeout:   f3.TimeoutKind(*graniteTimeout),
+	}
+	if err := graniteConfig.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	for i := 0; i < *iterations; i++ {
 		// Increment seed for successive iterations.
 		seed := *latencySeed + int64(i)
@@ -31,10 +44,6 @@ func main() {
 			LatencySeed: *latencySeed,
 			LatencyMean: *latencyMean,
 		}
-		graniteConfig := f3.GraniteConfig{
-			Delta:     *graniteDelta,
-			DeltaRate: *graniteDeltaRate,
-		}
 		sm := sim.NewSimulation(simConfig, graniteConfig, *traceLevel)
 
 		// Same chain for everyone.
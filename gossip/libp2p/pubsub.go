@@ -0,0 +1,98 @@
+// Package libp2p adapts libp2p pubsub to gossip.PubSub.
+// It is a separate module, so that depending on the gossip transport doesn't require libp2p.
+package libp2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/filecoin-project/go-f3/gossip"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Adapts a libp2p pubsub router, such as gossipsub, to gossip.PubSub.
+// Topics are joined when first used.
+type PubSub struct {
+	pubsub *pubsub.PubSub
+
+	mu     sync.Mutex
+	topics map[string]*pubsub.Topic
+}
+
+func NewPubSub(ps *pubsub.PubSub) *PubSub {
+	return &PubSub{
+		pubsub: ps,
+		topics: map[string]*pubsub.Topic{},
+	}
+}
+
+func (l *PubSub) Publish(ctx context.Context, topic string, data []byte) error {
+	t, err := l.join(topic)
+	if err != nil {
+		return err
+	}
+	err = t.Publish(ctx, data)
+	var validationErr pubsub.ValidationError
+	if errors.As(err, &validationErr) {
+		return fmt.Errorf("%w: %s", gossip.ErrRejected, validationErr.Reason)
+	}
+	return err
+}
+
+func (l *PubSub) Subscribe(topic string, handler func(data []byte)) (func(), error) {
+	t, err := l.join(topic)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := t.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				// The subscription was cancelled.
+				return
+			}
+			handler(msg.Data)
+		}
+	}()
+	return func() {
+		cancel()
+		sub.Cancel()
+	}, nil
+}
+
+func (l *PubSub) RegisterValidator(topic string, validator gossip.Validator) error {
+	return l.pubsub.RegisterTopicValidator(topic,
+		func(ctx context.Context, _ peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+			switch validator(ctx, msg.Data) {
+			case gossip.ValidationAccept:
+				return pubsub.ValidationAccept
+			case gossip.ValidationIgnore:
+				return pubsub.ValidationIgnore
+			default:
+				return pubsub.ValidationReject
+			}
+		})
+}
+
+// Returns the handle for a topic, joining it if not already joined.
+func (l *PubSub) join(topic string) (*pubsub.Topic, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t, ok := l.topics[topic]; ok {
+		return t, nil
+	}
+	t, err := l.pubsub.Join(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join topic %s: %w", topic, err)
+	}
+	l.topics[topic] = t
+	return t, nil
+}
@@ -0,0 +1,152 @@
+package libp2p
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/gossip"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	blankhost "github.com/libp2p/go-libp2p/p2p/host/blank"
+	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a handler recording messages to a channel.
+func recorder() (chan string, func(data []byte)) {
+	received := make(chan string, 10)
+	return received, func(data []byte) {
+		received <- string(data)
+	}
+}
+
+// Asserts that exactly the expected messages are received, in order.
+func requireReceived(t *testing.T, received chan string, expected ...string) {
+	for _, data := range expected {
+		select {
+		case actual := <-received:
+			require.Equal(t, data, actual)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q not received", data)
+		}
+	}
+	select {
+	case actual := <-received:
+		t.Fatalf("unexpected %q", actual)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// Rejects "bad" messages and ignores "boring" ones.
+func testValidator(_ context.Context, data []byte) gossip.ValidationResult {
+	switch string(data) {
+	case "bad":
+		return gossip.ValidationReject
+	case "boring":
+		return gossip.ValidationIgnore
+	}
+	return gossip.ValidationAccept
+}
+
+// A network with no peers, for a host which only talks to itself.
+// Methods a lone host doesn't use are not implemented.
+type loneNetwork struct {
+	network.Network
+	id        peer.ID
+	peerstore peerstore.Peerstore
+}
+
+func (n *loneNetwork) Peerstore() peerstore.Peerstore                    { return n.peerstore }
+func (n *loneNetwork) LocalPeer() peer.ID                                { return n.id }
+func (n *loneNetwork) Notify(network.Notifiee)                           {}
+func (n *loneNetwork) StopNotify(network.Notifiee)                       {}
+func (n *loneNetwork) SetStreamHandler(network.StreamHandler)            {}
+func (n *loneNetwork) Peers() []peer.ID                                  { return nil }
+func (n *loneNetwork) Conns() []network.Conn                             { return nil }
+func (n *loneNetwork) ConnsToPeer(peer.ID) []network.Conn                { return nil }
+func (n *loneNetwork) Connectedness(peer.ID) network.Connectedness       { return network.NotConnected }
+func (n *loneNetwork) ListenAddresses() []ma.Multiaddr                   { return nil }
+func (n *loneNetwork) InterfaceListenAddresses() ([]ma.Multiaddr, error) { return nil, nil }
+func (n *loneNetwork) Close() error                                      { return nil }
+
+// Creates a gossipsub router on a host with no peers, which delivers messages published locally
+// to local subscribers after validating them.
+func newLoopbackGossipSub(t *testing.T) *pubsub.PubSub {
+	priv, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPublicKey(pub)
+	require.NoError(t, err)
+	store, err := pstoremem.NewPeerstore()
+	require.NoError(t, err)
+	require.NoError(t, store.AddPrivKey(id, priv))
+	require.NoError(t, store.AddPubKey(id, pub))
+	host := blankhost.NewBlankHost(&loneNetwork{id: id, peerstore: store})
+	require.NotNil(t, host)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ps, err := pubsub.NewGossipSub(ctx, host)
+	require.NoError(t, err)
+	return ps
+}
+
+func TestPubSub(t *testing.T) {
+	ctx := context.Background()
+	ps := NewPubSub(newLoopbackGossipSub(t))
+	received, handler := recorder()
+	cancel, err := ps.Subscribe("topic", handler)
+	require.NoError(t, err)
+	other, otherHandler := recorder()
+	_, err = ps.Subscribe("other", otherHandler)
+	require.NoError(t, err)
+
+	require.NoError(t, ps.Publish(ctx, "topic", []byte("hello")))
+	requireReceived(t, received, "hello")
+	requireReceived(t, other)
+
+	// Messages failing validation are neither published nor delivered.
+	require.NoError(t, ps.RegisterValidator("topic", testValidator))
+	require.ErrorIs(t, ps.Publish(ctx, "topic", []byte("bad")), gossip.ErrRejected)
+	require.Error(t, ps.Publish(ctx, "topic", []byte("boring")))
+	require.NoError(t, ps.Publish(ctx, "topic", []byte("good")))
+	requireReceived(t, received, "good")
+
+	// A cancelled subscription receives nothing further.
+	cancel()
+	require.NoError(t, ps.Publish(ctx, "topic", []byte("again")))
+	requireReceived(t, received)
+}
+
+func TestTransportOverLibp2p(t *testing.T) {
+	transport := gossip.NewTransport(NewPubSub(newLoopbackGossipSub(t)), "test", 0)
+	delivered := make(chan *f3.GMessage, 10)
+	require.NoError(t, transport.Start(func(msg *f3.GMessage) {
+		delivered <- msg
+	}))
+	t.Cleanup(transport.Close)
+
+	base := f3.NewChain(f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1))
+	chain := base.Extend(f3.CIDOf([]byte("a")))
+	valid := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.QUALITY, Value: chain, Signature: []byte("sig")}
+	require.NoError(t, transport.Broadcast(valid))
+	select {
+	case msg := <-delivered:
+		require.Equal(t, valid, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s not delivered", valid)
+	}
+
+	invalid := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.QUALITY, Signature: []byte("sig")}
+	require.ErrorIs(t, transport.Broadcast(invalid), gossip.ErrRejected)
+	select {
+	case msg := <-delivered:
+		t.Fatalf("unexpected %s", msg)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
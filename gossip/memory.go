@@ -0,0 +1,133 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Capacity of each in-memory subscription's queue of messages awaiting its handler.
+// Messages arriving while the queue is full are dropped, as by a real gossip network.
+const memoryQueueLength = 1024
+
+// An in-memory pubsub network, connecting nodes within a process for integration tests.
+// Every message published by a node is validated by, and delivered to, every node, including the publisher.
+type MemoryNetwork struct {
+	mu    sync.Mutex
+	nodes []*MemoryPubSub
+}
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{}
+}
+
+// Adds a node to the network, returning its pubsub endpoint.
+func (n *MemoryNetwork) Join() *MemoryPubSub {
+	node := &MemoryPubSub{
+		network:       n,
+		validators:    map[string]Validator{},
+		subscriptions: map[string][]*memorySubscription{},
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nodes = append(n.nodes, node)
+	return node
+}
+
+// A node's endpoint in an in-memory pubsub network.
+type MemoryPubSub struct {
+	network *MemoryNetwork
+
+	mu            sync.Mutex
+	validators    map[string]Validator
+	subscriptions map[string][]*memorySubscription
+}
+
+type memorySubscription struct {
+	queue chan []byte
+	done  chan struct{}
+}
+
+func (m *MemoryPubSub) Publish(ctx context.Context, topic string, data []byte) error {
+	if result := m.validate(ctx, topic, data); result != ValidationAccept {
+		return fmt.Errorf("%w: validation result %d", ErrRejected, result)
+	}
+	m.network.mu.Lock()
+	nodes := append([]*MemoryPubSub{}, m.network.nodes...)
+	m.network.mu.Unlock()
+	for _, node := range nodes {
+		// The publisher's validator has already accepted the message.
+		if node == m || node.validate(ctx, topic, data) == ValidationAccept {
+			node.deliver(topic, data)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryPubSub) Subscribe(topic string, handler func(data []byte)) (func(), error) {
+	sub := &memorySubscription{
+		queue: make(chan []byte, memoryQueueLength),
+		done:  make(chan struct{}),
+	}
+	m.mu.Lock()
+	m.subscriptions[topic] = append(m.subscriptions[topic], sub)
+	m.mu.Unlock()
+	go func() {
+		for {
+			select {
+			case data := <-sub.queue:
+				handler(data)
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			subs := m.subscriptions[topic]
+			for i := range subs {
+				if subs[i] == sub {
+					m.subscriptions[topic] = append(subs[:i:i], subs[i+1:]...)
+					break
+				}
+			}
+			close(sub.done)
+		})
+	}, nil
+}
+
+func (m *MemoryPubSub) RegisterValidator(topic string, validator Validator) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.validators[topic]; ok {
+		return fmt.Errorf("duplicate validator for topic %s", topic)
+	}
+	m.validators[topic] = validator
+	return nil
+}
+
+func (m *MemoryPubSub) validate(ctx context.Context, topic string, data []byte) ValidationResult {
+	m.mu.Lock()
+	validator := m.validators[topic]
+	m.mu.Unlock()
+	if validator == nil {
+		return ValidationAccept
+	}
+	return validator(ctx, data)
+}
+
+// Queues a message for each of this node's subscriptions to a topic, dropping it for any whose queue is full.
+func (m *MemoryPubSub) deliver(topic string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sub := range m.subscriptions[topic] {
+		select {
+		case sub.queue <- data:
+		default:
+		}
+	}
+}
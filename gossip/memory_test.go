@@ -0,0 +1,82 @@
+package gossip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a handler recording messages to a channel.
+func recorder() (chan string, func(data []byte)) {
+	received := make(chan string, 10)
+	return received, func(data []byte) {
+		received <- string(data)
+	}
+}
+
+// Asserts that exactly the expected messages are received, in order.
+func requireReceived(t *testing.T, received chan string, expected ...string) {
+	for _, data := range expected {
+		select {
+		case actual := <-received:
+			require.Equal(t, data, actual)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q not received", data)
+		}
+	}
+	select {
+	case actual := <-received:
+		t.Fatalf("unexpected %q", actual)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// Rejects "bad" messages and ignores "boring" ones.
+func testValidator(_ context.Context, data []byte) ValidationResult {
+	switch string(data) {
+	case "bad":
+		return ValidationReject
+	case "boring":
+		return ValidationIgnore
+	}
+	return ValidationAccept
+}
+
+func TestMemoryPubSub(t *testing.T) {
+	ctx := context.Background()
+	network := NewMemoryNetwork()
+	a, b := network.Join(), network.Join()
+	receivedA, handlerA := recorder()
+	receivedB, handlerB := recorder()
+	_, err := a.Subscribe("topic", handlerA)
+	require.NoError(t, err)
+	cancelB, err := b.Subscribe("topic", handlerB)
+	require.NoError(t, err)
+	_, err = b.Subscribe("other", handlerB)
+	require.NoError(t, err)
+
+	// Messages are delivered to every subscriber, including the publisher.
+	require.NoError(t, a.Publish(ctx, "topic", []byte("hello")))
+	require.NoError(t, b.Publish(ctx, "topic", []byte("world")))
+	requireReceived(t, receivedA, "hello", "world")
+	requireReceived(t, receivedB, "hello", "world")
+
+	// A receiver's validator filters messages it receives, and a publisher's filters those it publishes.
+	require.NoError(t, b.RegisterValidator("topic", testValidator))
+	require.Error(t, b.RegisterValidator("topic", testValidator))
+	require.NoError(t, a.Publish(ctx, "topic", []byte("bad")))
+	require.NoError(t, a.Publish(ctx, "topic", []byte("boring")))
+	requireReceived(t, receivedA, "bad", "boring")
+	requireReceived(t, receivedB)
+	require.ErrorIs(t, b.Publish(ctx, "topic", []byte("bad")), ErrRejected)
+	requireReceived(t, receivedA)
+
+	// A cancelled subscription receives nothing further.
+	cancelB()
+	cancelB()
+	require.NoError(t, a.Publish(ctx, "topic", []byte("again")))
+	requireReceived(t, receivedA, "again")
+	requireReceived(t, receivedB)
+}
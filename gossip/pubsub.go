@@ -0,0 +1,40 @@
+// Package gossip provides a transport carrying participants' messages over topic-based gossip,
+// with an in-memory implementation for tests. The gossip/libp2p module adapts libp2p pubsub.
+package gossip
+
+import (
+	"context"
+	"errors"
+)
+
+// Error returned (wrapped) when publishing a message which fails local validation.
+var ErrRejected = errors.New("message rejected by validator")
+
+// Outcome of validating a message before it is delivered to subscribers or forwarded to other peers.
+type ValidationResult int
+
+const (
+	// The message is valid: it is delivered and forwarded.
+	ValidationAccept ValidationResult = iota
+	// The message is invalid: it is dropped, and its sender may be penalised.
+	ValidationReject
+	// The message is dropped without penalising its sender, such as one which is valid but not useful.
+	ValidationIgnore
+)
+
+// Checks a message published to a topic.
+type Validator func(ctx context.Context, data []byte) ValidationResult
+
+// Topic-based publish/subscribe messaging between peers.
+type PubSub interface {
+	// Publishes a message to a topic.
+	// Returns an error wrapping ErrRejected if the topic's local validator does not accept it.
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribes to a topic, invoking a handler with each message published to it which passes validation,
+	// including those published locally, until the returned function is called to cancel the subscription.
+	// The handler is invoked from a single goroutine for each subscription.
+	Subscribe(topic string, handler func(data []byte)) (cancel func(), err error)
+	// Registers the validator through which every message published to a topic must pass,
+	// whether published locally or received from a peer. A topic has at most one validator.
+	RegisterValidator(topic string, validator Validator) error
+}
@@ -0,0 +1,135 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// Wrapped by a check's error for a message which the transport should drop without forwarding,
+// but without penalising the peer which forwarded it, since the message may yet prove valid.
+var ErrIgnore = errors.New("message ignored")
+
+// Returns the name of the topic carrying GossiPBFT messages in a network.
+func TopicName(networkName string) string {
+	return "/f3/granite/0.0.1/" + networkName
+}
+
+// A transport carrying a participant's messages over a pubsub topic, implementing node.Transport.
+// Messages are published in their binary encoding.
+// Each message is validated before it is delivered or forwarded, with the same stateless checks
+// an instance makes of each message it receives, and optionally further checks.
+type Transport struct {
+	pubsub         PubSub
+	topic          string
+	maxChainLength int
+	// Further checks of each decoded message. May be nil.
+	check func(msg *f3.GMessage) error
+	// Cancels the subscription, once started.
+	cancel func()
+}
+
+// Creates a transport for messages in a network, rejecting messages with chains of more than maxChainLength tipsets.
+// A zero maxChainLength means unbounded.
+func NewTransport(pubsub PubSub, networkName string, maxChainLength int) *Transport {
+	return &Transport{
+		pubsub:         pubsub,
+		topic:          TopicName(networkName),
+		maxChainLength: maxChainLength,
+	}
+}
+
+// Sets further checks which each message must pass to be delivered and forwarded,
+// such as that its sender is in the power table. A message failing the checks is rejected,
+// unless the error wraps ErrIgnore. This must be called before starting the transport.
+func (t *Transport) SetCheck(check func(msg *f3.GMessage) error) {
+	t.check = check
+}
+
+// Returns a check, for SetCheck, that a message's sender is in the current power table
+// and that the message is signed with the sender's key, so that the transport drops and doesn't forward
+// messages which no participant would accept. All messages pass while the power table is empty.
+// The current power table, and the instance to which it belongs, are fetched for each message
+// from any goroutine, such as from the state snapshot of a node.Participant.
+// The power table of another instance may differ, so a message from a sender not in the current table,
+// or for another instance with an invalid signature, is ignored rather than rejected.
+func PowerTableCheck(powerTable func() (f3.PowerTable, int), verifier f3.Verifier) func(msg *f3.GMessage) error {
+	return func(msg *f3.GMessage) error {
+		table, instance := powerTable()
+		if len(table.Entries) == 0 {
+			return nil
+		}
+		_, pubKey := table.Get(msg.Sender)
+		if pubKey == nil {
+			return fmt.Errorf("%w: sender %d not in power table of instance %d", ErrIgnore, msg.Sender, instance)
+		}
+		if !verifier.Verify(pubKey, msg.SignaturePayload(), msg.Signature) {
+			if msg.Instance != instance {
+				return fmt.Errorf("%w: invalid signature from %d for instance %d with power table of instance %d",
+					ErrIgnore, msg.Sender, msg.Instance, instance)
+			}
+			return fmt.Errorf("invalid signature from %d", msg.Sender)
+		}
+		return nil
+	}
+}
+
+// Starts delivering each valid message published to the topic, including those published locally.
+// Delivery is from a single goroutine, and should not block for long.
+func (t *Transport) Start(deliver func(msg *f3.GMessage)) error {
+	if t.cancel != nil {
+		return errors.New("transport already started")
+	}
+	if err := t.pubsub.RegisterValidator(t.topic, t.validate); err != nil {
+		return err
+	}
+	cancel, err := t.pubsub.Subscribe(t.topic, func(data []byte) {
+		// The message has passed validation, so decodes.
+		var msg f3.GMessage
+		if err := msg.UnmarshalBinary(data); err == nil {
+			deliver(&msg)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	t.cancel = cancel
+	return nil
+}
+
+// Stops delivering messages.
+func (t *Transport) Close() {
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+func (t *Transport) Broadcast(msg *f3.GMessage) error {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", msg, err)
+	}
+	return t.pubsub.Publish(context.Background(), t.topic, data)
+}
+
+// Rejects a message which can't be decoded, isn't well formed, or fails the further checks,
+// and ignores one for which the checks return ErrIgnore.
+func (t *Transport) validate(_ context.Context, data []byte) ValidationResult {
+	var msg f3.GMessage
+	if err := msg.UnmarshalBinary(data); err != nil {
+		return ValidationReject
+	}
+	if err := msg.Validate(t.maxChainLength); err != nil {
+		return ValidationReject
+	}
+	if t.check != nil {
+		if err := t.check(&msg); errors.Is(err, ErrIgnore) {
+			return ValidationIgnore
+		} else if err != nil {
+			return ValidationReject
+		}
+	}
+	return ValidationAccept
+}
@@ -0,0 +1,195 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/node"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+var base = f3.NewChain(f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1))
+
+// Starts a transport, returning a channel of the messages it delivers.
+func startTransport(t *testing.T, transport *Transport) chan *f3.GMessage {
+	delivered := make(chan *f3.GMessage, 10)
+	require.NoError(t, transport.Start(func(msg *f3.GMessage) {
+		delivered <- msg
+	}))
+	t.Cleanup(transport.Close)
+	return delivered
+}
+
+// Asserts that exactly the expected messages are delivered, in order.
+func requireDelivered(t *testing.T, delivered chan *f3.GMessage, expected ...*f3.GMessage) {
+	for _, msg := range expected {
+		select {
+		case actual := <-delivered:
+			require.Equal(t, msg, actual)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not delivered", msg)
+		}
+	}
+	select {
+	case actual := <-delivered:
+		t.Fatalf("unexpected %s", actual)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestTransport(t *testing.T) {
+	network := NewMemoryNetwork()
+	a, b := network.Join(), network.Join()
+	transportA := NewTransport(a, "test", 4)
+	transportB := NewTransport(b, "test", 4)
+	transportB.SetCheck(func(msg *f3.GMessage) error {
+		if msg.Sender != 1 {
+			return errors.New("unknown sender")
+		}
+		return nil
+	})
+	deliveredA := startTransport(t, transportA)
+	deliveredB := startTransport(t, transportB)
+	require.Error(t, transportA.Start(func(*f3.GMessage) {}))
+
+	chain := base.Extend(f3.CIDOf([]byte("a")))
+	valid := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.QUALITY, Value: chain, Signature: []byte("sig")}
+	require.NoError(t, transportA.Broadcast(valid))
+	requireDelivered(t, deliveredA, valid)
+	requireDelivered(t, deliveredB, valid)
+
+	// A message which is not well formed is rejected when published.
+	invalid := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.CONVERGE, Value: chain, Signature: []byte("sig")}
+	require.ErrorIs(t, transportA.Broadcast(invalid), ErrRejected)
+	tooLong := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.QUALITY, Signature: []byte("sig"),
+		Value: chain.Extend(f3.CIDOf([]byte("b"))).Extend(f3.CIDOf([]byte("c"))).Extend(f3.CIDOf([]byte("d")))}
+	require.ErrorIs(t, transportA.Broadcast(tooLong), ErrRejected)
+
+	// A message failing a receiver's checks, or which doesn't decode, is not delivered to it.
+	unknown := &f3.GMessage{Sender: 2, Instance: 0, Round: 0, Step: f3.QUALITY, Value: chain, Signature: []byte("sig")}
+	require.NoError(t, transportA.Broadcast(unknown))
+	// A node with no validator can publish anything.
+	require.NoError(t, network.Join().Publish(context.Background(), TopicName("test"), []byte("garbage")))
+	requireDelivered(t, deliveredA, unknown)
+	requireDelivered(t, deliveredB)
+
+	// Messages on another network's topic are not delivered.
+	other := NewTransport(a, "other", 4)
+	startTransport(t, other)
+	require.NoError(t, other.Broadcast(valid))
+	requireDelivered(t, deliveredA)
+}
+
+func TestPowerTableCheck(t *testing.T) {
+	signer := sim.NewFakeSigner()
+	var table f3.PowerTable
+	check := PowerTableCheck(func() (f3.PowerTable, int) { return table, 0 }, signer)
+	chain := base.Extend(f3.CIDOf([]byte("a")))
+	msg := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.QUALITY, Value: chain}
+	msg.Signature = signer.Sign(sim.FakePubKey(1), msg.SignaturePayload())
+	unknown := &f3.GMessage{Sender: 2, Instance: 0, Round: 0, Step: f3.QUALITY, Value: chain}
+	unknown.Signature = signer.Sign(sim.FakePubKey(2), unknown.SignaturePayload())
+	forged := *msg
+	forged.Signature = []byte("forged")
+	later := forged
+	later.Instance = 1
+
+	// Anything passes before the power table is known.
+	require.NoError(t, check(unknown))
+
+	table = f3.NewPowerTable()
+	table.Add(1, big.NewInt(1), sim.FakePubKey(1))
+	require.NoError(t, check(msg))
+	require.ErrorIs(t, check(unknown), ErrIgnore)
+	err := check(&forged)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrIgnore)
+	// The sender's key may differ in the power table of another instance.
+	require.ErrorIs(t, check(&later), ErrIgnore)
+}
+
+func TestTransportIgnoresSenderJoiningNextInstance(t *testing.T) {
+	signer := sim.NewFakeSigner()
+	current := f3.NewPowerTable()
+	current.Add(1, big.NewInt(1), sim.FakePubKey(1))
+	next := f3.NewPowerTable()
+	next.Add(1, big.NewInt(1), sim.FakePubKey(1))
+	next.Add(2, big.NewInt(1), sim.FakePubKey(2))
+	table, instance := current, 0
+	transport := NewTransport(NewMemoryNetwork().Join(), "test", 0)
+	transport.SetCheck(PowerTableCheck(func() (f3.PowerTable, int) { return table, instance }, signer))
+	encode := func(msg *f3.GMessage) []byte {
+		data, err := msg.MarshalBinary()
+		require.NoError(t, err)
+		return data
+	}
+
+	// A sender joining in the next instance's power table is ignored, not rejected, until that table is current.
+	chain := base.Extend(f3.CIDOf([]byte("a")))
+	joining := &f3.GMessage{Sender: 2, Instance: 1, Round: 0, Step: f3.QUALITY, Value: chain}
+	joining.Signature = signer.Sign(sim.FakePubKey(2), joining.SignaturePayload())
+	require.Equal(t, ValidationIgnore, transport.validate(context.Background(), encode(joining)))
+
+	// Messages which don't decode, aren't well formed, or are badly signed for the current instance are rejected.
+	forged := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.QUALITY, Value: chain, Signature: []byte("forged")}
+	malformed := &f3.GMessage{Sender: 1, Instance: 0, Round: 0, Step: f3.CONVERGE, Value: chain}
+	malformed.Signature = signer.Sign(sim.FakePubKey(1), malformed.SignaturePayload())
+	require.Equal(t, ValidationReject, transport.validate(context.Background(), []byte("garbage")))
+	require.Equal(t, ValidationReject, transport.validate(context.Background(), encode(malformed)))
+	require.Equal(t, ValidationReject, transport.validate(context.Background(), encode(forged)))
+
+	table, instance = next, 1
+	require.Equal(t, ValidationAccept, transport.validate(context.Background(), encode(joining)))
+}
+
+func TestParticipantsOverGossip(t *testing.T) {
+	const count = 4
+	config := f3.GraniteConfig{Delta: 0.05, DeltaRate: 0.05}
+	signer := sim.NewFakeSigner()
+	powerTables := sim.NewPowerTables()
+	power := f3.NewPowerTable()
+	network := NewMemoryNetwork()
+	participants := make([]*node.Participant, count)
+	for i := range participants {
+		id := f3.ActorID(i)
+		transport := NewTransport(network.Join(), "test", 0)
+		p, err := node.NewParticipant(id, config, transport, f3.NewFakeVRF(), signer, powerTables, sim.NewBeacons())
+		require.NoError(t, err)
+		participants[i] = p
+		transport.SetCheck(PowerTableCheck(func() (f3.PowerTable, int) {
+			state := p.State()
+			return state.PowerTable, state.PowerTableInstance
+		}, signer))
+		require.NoError(t, transport.Start(func(msg *f3.GMessage) {
+			_ = p.SubmitMessage(msg)
+		}))
+		t.Cleanup(transport.Close)
+		power.Add(id, big.NewInt(1), sim.FakePubKey(id))
+	}
+	powerTables.Set(0, power)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chain := base.Extend(f3.CIDOf([]byte("a")))
+	for _, p := range participants {
+		go func(p *node.Participant) { _ = p.Run(ctx) }(p)
+		require.NoError(t, p.SubmitCanonicalChain(chain))
+	}
+	require.Eventually(t, func() bool {
+		for _, p := range participants {
+			if p.FinalityCertificate() == nil {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, time.Millisecond)
+	for _, p := range participants {
+		finalised, _ := p.Finalised()
+		require.Equal(t, *chain.Head(), finalised)
+	}
+}
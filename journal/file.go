@@ -0,0 +1,198 @@
+// Package journal provides durable storage for a participant's journal.
+package journal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+var (
+	// Error returned (wrapped) when a journal file is corrupt other than by a torn final record.
+	ErrCorrupt = errors.New("corrupt journal")
+	// Error returned (wrapped) by every change to a journal after a write failed in a way that leaves
+	// the file's contents unknown. The journal must be reopened.
+	ErrFailed = errors.New("journal failed")
+)
+
+// Length of each record's header: the payload length and its checksum.
+const headerLength = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// A journal stored in a file as a sequence of records, each an encoded entry prefixed by its length and checksum.
+// Each append is synced to disk before returning.
+// A crash while appending may leave a torn final record, which is discarded when the journal is opened.
+// A failure to write or sync leaves the journal permanently failed, refusing further changes.
+type FileJournal struct {
+	path string
+	file *os.File
+	// The error which failed the journal, if any.
+	failed error
+}
+
+// Opens the journal at a path, creating it if it doesn't exist, and discarding any torn final record.
+func Open(path string) (*FileJournal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	_, valid, err := readRecords(file)
+	if err == nil {
+		err = file.Truncate(valid)
+	}
+	if err == nil {
+		_, err = file.Seek(valid, io.SeekStart)
+	}
+	if err == nil {
+		// Make the file's creation durable.
+		err = syncDir(filepath.Dir(path))
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &FileJournal{path: path, file: file}, nil
+}
+
+func (j *FileJournal) Append(entry *f3.JournalEntry) error {
+	if j.failed != nil {
+		return j.failed
+	}
+	record, err := encodeRecord(entry)
+	if err != nil {
+		return err
+	}
+	// A partial write or failed sync leaves a record which may or may not be durable.
+	if _, err := j.file.Write(record); err != nil {
+		return j.fail(err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return j.fail(err)
+	}
+	return nil
+}
+
+func (j *FileJournal) Entries() ([]*f3.JournalEntry, error) {
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries, _, err := readRecords(file)
+	return entries, err
+}
+
+// Replaces the journal by writing the new entries to a temporary file, syncing it,
+// and renaming it over the journal. Subsequent entries are appended to the replacement file.
+// A failure once the file is renamed leaves the journal failed, since the rename may not be durable.
+func (j *FileJournal) Rewrite(entries []*f3.JournalEntry) error {
+	if j.failed != nil {
+		return j.failed
+	}
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		var record []byte
+		if record, err = encodeRecord(entry); err != nil {
+			break
+		}
+		if _, err = tmp.Write(record); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, j.path)
+	}
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	// The journal's path now names the replacement file, so continue with it whatever happens next.
+	_ = j.file.Close()
+	j.file = tmp
+	if err := syncDir(filepath.Dir(j.path)); err != nil {
+		return j.fail(err)
+	}
+	return nil
+}
+
+func (j *FileJournal) Close() error {
+	return j.file.Close()
+}
+
+// Fails the journal permanently, returning the error it then returns for every change.
+func (j *FileJournal) fail(err error) error {
+	j.failed = fmt.Errorf("%w: %w", ErrFailed, err)
+	return j.failed
+}
+
+func encodeRecord(entry *f3.JournalEntry) ([]byte, error) {
+	payload, err := entry.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, headerLength, headerLength+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+	return append(record, payload...), nil
+}
+
+// Reads records from the start of a file, returning the entries and the length of the valid records.
+// A final record which is incomplete or fails its checksum is treated as torn and ignored.
+func readRecords(file *os.File) ([]*f3.JournalEntry, int64, error) {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<62))
+	if err != nil {
+		return nil, 0, err
+	}
+	var entries []*f3.JournalEntry
+	pos := 0
+	for pos < len(data) {
+		if len(data)-pos < headerLength {
+			break
+		}
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + headerLength + length
+		if length > len(data) || end > len(data) {
+			break
+		}
+		payload := data[pos+headerLength : end]
+		var entry f3.JournalEntry
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(data[pos+4:pos+8]) {
+			if end == len(data) {
+				break
+			}
+			return nil, 0, fmt.Errorf("%w: checksum mismatch at offset %d", ErrCorrupt, pos)
+		}
+		if err := entry.UnmarshalBinary(payload); err != nil {
+			return nil, 0, fmt.Errorf("%w: at offset %d: %w", ErrCorrupt, pos, err)
+		}
+		entries = append(entries, &entry)
+		pos = end
+	}
+	return entries, int64(pos), nil
+}
+
+// Syncs a directory, making the creation or renaming of files in it durable.
+// A variable so that tests can inject failures.
+var syncDir = func(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,154 @@
+package journal
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/stretchr/testify/require"
+)
+
+func testEntries() []*f3.JournalEntry {
+	base := f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1)
+	chain := f3.NewChain(base, f3.NewTipSet(101, f3.CIDOf([]byte("a")), 2))
+	return []*f3.JournalEntry{
+		{Kind: f3.JournalStart, Instance: 1, Input: chain},
+		{Kind: f3.JournalSend, Instance: 1, Proposal: chain, Value: chain,
+			Message: &f3.GMessage{Sender: 2, Instance: 1, Step: f3.QUALITY, Value: chain, Signature: []byte("sig")}},
+		{Kind: f3.JournalDecide, Instance: 1, Certificate: &f3.FinalityCertificate{Instance: 1, Value: chain,
+			Signers: f3.NewBitfield(0, 2), SignersPower: big.NewInt(2), Signature: []byte("agg")}},
+	}
+}
+
+func TestAppendAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := Open(path)
+	require.NoError(t, err)
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	expected := testEntries()
+	for _, entry := range expected {
+		require.NoError(t, j.Append(entry))
+	}
+	entries, err = j.Entries()
+	require.NoError(t, err)
+	require.Equal(t, expected, entries)
+	require.NoError(t, j.Close())
+
+	j, err = Open(path)
+	require.NoError(t, err)
+	defer j.Close()
+	entries, err = j.Entries()
+	require.NoError(t, err)
+	require.Equal(t, expected, entries)
+}
+
+func TestTornRecordDiscarded(t *testing.T) {
+	expected := testEntries()
+	record, err := encodeRecord(expected[1])
+	require.NoError(t, err)
+	for _, torn := range [][]byte{
+		record[:3],
+		record[:headerLength+5],
+		record[:len(record)-1],
+		// A complete record whose contents were not all written.
+		append(append([]byte{}, record[:len(record)-1]...), record[len(record)-1]^0xff),
+	} {
+		path := filepath.Join(t.TempDir(), "journal")
+		j, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, j.Append(expected[0]))
+		require.NoError(t, j.Close())
+		appendBytes(t, path, torn)
+
+		j, err = Open(path)
+		require.NoError(t, err)
+		entries, err := j.Entries()
+		require.NoError(t, err)
+		require.Equal(t, expected[:1], entries)
+
+		// Appending continues after the last valid record.
+		require.NoError(t, j.Append(expected[2]))
+		entries, err = j.Entries()
+		require.NoError(t, err)
+		require.Equal(t, []*f3.JournalEntry{expected[0], expected[2]}, entries)
+		require.NoError(t, j.Close())
+	}
+}
+
+func TestCorruptRecordRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := Open(path)
+	require.NoError(t, err)
+	for _, entry := range testEntries() {
+		require.NoError(t, j.Append(entry))
+	}
+	require.NoError(t, j.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[headerLength+1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	_, err = Open(path)
+	require.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := Open(path)
+	require.NoError(t, err)
+	defer j.Close()
+	expected := testEntries()
+	for _, entry := range expected[:2] {
+		require.NoError(t, j.Append(entry))
+	}
+
+	require.NoError(t, j.Rewrite(expected[2:]))
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Equal(t, expected[2:], entries)
+	require.NoFileExists(t, path+".tmp")
+
+	require.NoError(t, j.Append(expected[0]))
+	entries, err = j.Entries()
+	require.NoError(t, err)
+	require.Equal(t, []*f3.JournalEntry{expected[2], expected[0]}, entries)
+}
+
+func TestRewriteFailureFailsJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := Open(path)
+	require.NoError(t, err)
+	defer j.Close()
+	expected := testEntries()
+	require.NoError(t, j.Append(expected[0]))
+
+	// A failure after the replacement is renamed over the journal fails it.
+	syncErr := errors.New("sync failed")
+	realSyncDir := syncDir
+	syncDir = func(string) error { return syncErr }
+	t.Cleanup(func() { syncDir = realSyncDir })
+	err = j.Rewrite(expected[2:])
+	require.ErrorIs(t, err, ErrFailed)
+	require.ErrorIs(t, err, syncErr)
+	syncDir = realSyncDir
+
+	require.ErrorIs(t, j.Append(expected[0]), ErrFailed)
+	require.ErrorIs(t, j.Rewrite(expected), ErrFailed)
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Equal(t, expected[2:], entries)
+}
+
+func appendBytes(t *testing.T, path string, data []byte) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
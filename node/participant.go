@@ -0,0 +1,163 @@
+package node
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// A participant which is safe to use from many goroutines, hosted in its own runtime.
+// All inputs are serialised through the runtime's event loop, so they never re-enter the participant.
+// Submit methods never block: they return ErrQueueFull while the event queue is full,
+// so that callers such as gossip validators can shed load rather than stall.
+// Chains and certificates are queued separately from messages and delivered first,
+// so only messages are shed under a flood of gossip.
+type Participant struct {
+	runtime     *Runtime
+	participant *f3.Participant
+
+	mu sync.RWMutex
+	// The participant's state as of the last event processed.
+	state State
+}
+
+// A snapshot of a participant's progress.
+type State struct {
+	// The running instance, or the next instance if none is running.
+	Instance int
+	// The round of the running instance, or -1 if none is running.
+	Round int
+	// The last finalised tipset and the round in which it was decided.
+	Finalised      f3.TipSet
+	FinalisedRound int
+	// Certificate for the last decided instance, or nil if none has been decided.
+	Certificate *f3.FinalityCertificate
+	// Power table of the latest instance begun or decided, which is empty before any instance begins,
+	// and that instance.
+	PowerTable         f3.PowerTable
+	PowerTableInstance int
+	// The error which halted the participant, or nil if it is running.
+	Failure error
+}
+
+// Creates a participant hosted in a new runtime, which broadcasts messages with a transport.
+// Returns an error wrapping f3.ErrInvalidConfig if the configuration is invalid.
+func NewParticipant(id f3.ActorID, config f3.GraniteConfig, transport Transport, vrf f3.VRFer,
+	signer f3.SignerVerifier, powerTables f3.PowerTableProvider, beacons f3.BeaconProvider) (*Participant, error) {
+	runtime := NewRuntime(transport)
+	participant, err := f3.NewParticipant(id, config, runtime, vrf, signer, powerTables, beacons)
+	if err != nil {
+		return nil, err
+	}
+	p := &Participant{
+		runtime:     runtime,
+		participant: participant,
+	}
+	p.refresh()
+	return p, nil
+}
+
+func (p *Participant) ID() f3.ActorID {
+	return p.participant.ID()
+}
+
+// Returns the runtime hosting the participant, such as to set its logger.
+func (p *Participant) Runtime() *Runtime {
+	return p.runtime
+}
+
+// Configures the underlying participant, such as to set its chain store or journal, or register listeners.
+// Listeners are invoked from the event loop, and must not block.
+// This must be called before the participant runs.
+func (p *Participant) Setup(setup func(participant *f3.Participant) error) error {
+	defer p.refresh()
+	return setup(p.participant)
+}
+
+// Runs the participant's event loop until the context is cancelled, returning the context's error.
+func (p *Participant) Run(ctx context.Context) error {
+	return p.runtime.Run(ctx, loopReceiver{p})
+}
+
+// Submits a message from another participant, returning ErrQueueFull or ErrStopped if it is not accepted.
+func (p *Participant) SubmitMessage(msg *f3.GMessage) error {
+	return p.runtime.tryEnqueue(p.runtime.events, messageEvent(msg))
+}
+
+// Submits a canonical chain, returning ErrQueueFull or ErrStopped if it is not accepted.
+func (p *Participant) SubmitCanonicalChain(chain f3.ECChain) error {
+	return p.runtime.tryEnqueue(p.runtime.local, chainEvent(chain))
+}
+
+// Submits a finality certificate for a later instance, returning ErrQueueFull or ErrStopped if it is not accepted.
+// A certificate which fails validation when processed is logged and dropped.
+func (p *Participant) SubmitFinalityCertificate(cert *f3.FinalityCertificate) error {
+	return p.runtime.tryEnqueue(p.runtime.local, func(f3.Receiver) {
+		if err := p.participant.ReceiveFinalityCertificate(cert); err != nil {
+			p.runtime.Log("P%d: dropping %s: %s", p.ID(), cert, err)
+		}
+		p.refresh()
+	})
+}
+
+// Returns a snapshot of the participant's state as of the last event processed.
+func (p *Participant) State() State {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.state
+}
+
+// Returns the last finalised tipset and the round in which it was decided.
+func (p *Participant) Finalised() (f3.TipSet, int) {
+	state := p.State()
+	return state.Finalised, state.FinalisedRound
+}
+
+// Returns the certificate for the last decided instance, or nil if no instance has been decided.
+func (p *Participant) FinalityCertificate() *f3.FinalityCertificate {
+	return p.State().Certificate
+}
+
+// Updates the snapshot of the participant's state. Called only from the event loop, or before it runs.
+func (p *Participant) refresh() {
+	finalised, finalisedRound := p.participant.Finalised()
+	powerTable, powerTableInstance := p.participant.PowerTable()
+	state := State{
+		Instance:           p.participant.CurrentInstance(),
+		Round:              p.participant.CurrentRound(),
+		Finalised:          finalised,
+		FinalisedRound:     finalisedRound,
+		Certificate:        p.participant.FinalityCertificate(),
+		PowerTable:         powerTable,
+		PowerTableInstance: powerTableInstance,
+		Failure:            p.participant.Failure(),
+	}
+	p.mu.Lock()
+	p.state = state
+	p.mu.Unlock()
+}
+
+// Delivers events from the event loop to the participant, updating the snapshot after each.
+type loopReceiver struct {
+	p *Participant
+}
+
+func (r loopReceiver) ID() f3.ActorID {
+	return r.p.ID()
+}
+
+func (r loopReceiver) ReceiveCanonicalChain(chain f3.ECChain) {
+	r.p.participant.ReceiveCanonicalChain(chain)
+	r.p.refresh()
+}
+
+func (r loopReceiver) ReceiveMessage(msg *f3.GMessage) {
+	r.p.participant.ReceiveMessage(msg)
+	r.p.refresh()
+}
+
+func (r loopReceiver) ReceiveAlarm(alarm f3.Alarm) {
+	r.p.participant.ReceiveAlarm(alarm)
+	r.p.refresh()
+}
@@ -0,0 +1,102 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+// A transport submitting each message to other participants from a new goroutine, retrying while they are busy.
+type gossip struct {
+	participants []*Participant
+}
+
+func (g *gossip) Broadcast(msg *f3.GMessage) error {
+	for _, p := range g.participants {
+		go func(p *Participant) {
+			for errors.Is(p.SubmitMessage(msg), ErrQueueFull) {
+				time.Sleep(time.Millisecond)
+			}
+		}(p)
+	}
+	return nil
+}
+
+func TestConcurrentParticipants(t *testing.T) {
+	const count = 4
+	config := f3.GraniteConfig{Delta: 0.05, DeltaRate: 0.05}
+	signer := sim.NewFakeSigner()
+	powerTables := sim.NewPowerTables()
+	power := f3.NewPowerTable()
+	transport := &gossip{}
+	for i := 0; i < count; i++ {
+		id := f3.ActorID(i)
+		p, err := NewParticipant(id, config, transport, f3.NewFakeVRF(), signer, powerTables, sim.NewBeacons())
+		require.NoError(t, err)
+		transport.participants = append(transport.participants, p)
+		power.Add(id, big.NewInt(1), sim.FakePubKey(id))
+	}
+	powerTables.Set(0, power)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	base := f3.NewChain(f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1))
+	chain := base.Extend(f3.CIDOf([]byte("a")))
+	for _, p := range transport.participants {
+		require.Nil(t, p.FinalityCertificate())
+		go func(p *Participant) { _ = p.Run(ctx) }(p)
+		require.NoError(t, p.SubmitCanonicalChain(chain))
+	}
+
+	// Snapshots are safe to read while the participants run.
+	require.Eventually(t, func() bool {
+		for _, p := range transport.participants {
+			if p.FinalityCertificate() == nil {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, time.Millisecond)
+	for _, p := range transport.participants {
+		state := p.State()
+		require.Equal(t, *chain.Head(), state.Finalised)
+		require.Equal(t, 0, state.Certificate.Instance)
+		require.Equal(t, 1, state.Instance)
+	}
+}
+
+func TestSubmitBackPressure(t *testing.T) {
+	p, err := NewParticipant(0, f3.GraniteConfig{}, nopTransport{}, f3.NewFakeVRF(), sim.NewFakeSigner(),
+		sim.NewPowerTables(), sim.NewBeacons())
+	require.NoError(t, err)
+	require.Equal(t, State{Instance: 0, Round: -1}, p.State())
+
+	// Submissions are rejected, rather than blocking, while the queue is full.
+	msg := &f3.GMessage{Sender: 1, Instance: 5, Step: f3.QUALITY}
+	for i := 0; i < eventQueueLength; i++ {
+		require.NoError(t, p.SubmitMessage(msg))
+	}
+	require.ErrorIs(t, p.SubmitMessage(msg), ErrQueueFull)
+	// Chains are queued separately, so aren't shed with messages.
+	for i := 0; i < localQueueLength; i++ {
+		require.NoError(t, p.SubmitCanonicalChain(nil))
+	}
+	require.ErrorIs(t, p.SubmitCanonicalChain(nil), ErrQueueFull)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		return p.SubmitMessage(msg) == nil
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.ErrorIs(t, p.SubmitMessage(msg), ErrStopped)
+}
@@ -0,0 +1,209 @@
+// Package node provides a runtime hosting a participant in a real node, with wall-clock time and a network transport.
+package node
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// Capacity of the queue of messages from other participants awaiting delivery to the participant.
+// Senders of messages block while the queue is full.
+const eventQueueLength = 1024
+
+// Capacity of the queue of local events, i.e. chains, certificates and alarms, awaiting delivery.
+// Local events are delivered before any queued message, so a flood of gossip can't delay or shed them.
+const localQueueLength = 64
+
+// Errors returned when submitting an event without blocking.
+var (
+	// The event's queue is full. The caller may retry later or drop the event.
+	ErrQueueFull = errors.New("event queue full")
+	// The event loop has stopped.
+	ErrStopped = errors.New("runtime stopped")
+)
+
+// Sends messages to other participants.
+type Transport interface {
+	// Sends a message to all other participants.
+	// This is called from the runtime's event loop, so should not block on the network.
+	Broadcast(msg *f3.GMessage) error
+}
+
+// A runtime hosting a single participant, implementing f3.Network with a monotonic wall clock and real timers.
+// Messages and chains received from other goroutines, and alarms, are delivered to the participant
+// one at a time from a single event loop goroutine, so the participant needs no synchronisation.
+// Local events (chains, certificates and alarms) are queued separately from messages, and delivered first.
+// The participant may be set up, e.g. restored from a journal, before the runtime runs, from the goroutine
+// which then runs it, but must be driven only through the runtime once it is running.
+type Runtime struct {
+	transport Transport
+	logf      func(format string, args ...interface{})
+	// Reference point for the monotonic clock.
+	start time.Time
+	// Messages from other participants awaiting delivery to the participant.
+	events chan func(p f3.Receiver)
+	// Local events awaiting delivery to the participant, which take priority over messages.
+	local chan func(p f3.Receiver)
+	// Closed when the event loop stops.
+	stopped chan struct{}
+	// Pending timer for each alarm. Accessed only from the event loop.
+	alarms map[alarmKey]*time.Timer
+}
+
+type alarmKey struct {
+	sender f3.ActorID
+	alarm  f3.Alarm
+}
+
+// Creates a runtime which broadcasts messages with a transport.
+// The runtime's clock starts at zero when it is created.
+func NewRuntime(transport Transport) *Runtime {
+	return &Runtime{
+		transport: transport,
+		logf:      func(string, ...interface{}) {},
+		start:     time.Now(),
+		events:    make(chan func(p f3.Receiver), eventQueueLength),
+		local:     make(chan func(p f3.Receiver), localQueueLength),
+		stopped:   make(chan struct{}),
+		alarms:    map[alarmKey]*time.Timer{},
+	}
+}
+
+// Sets a function to which the participant's and runtime's log messages are written.
+// By default, log messages are discarded. This must be called before the runtime runs.
+func (r *Runtime) SetLogger(logf func(format string, args ...interface{})) {
+	r.logf = logf
+}
+
+// Runs the event loop, delivering events to a participant until the context is cancelled.
+// On cancellation, pending alarms are cancelled and subsequently received messages and chains are dropped.
+// Returns the context's error.
+func (r *Runtime) Run(ctx context.Context, participant f3.Receiver) error {
+	defer func() {
+		close(r.stopped)
+		for key, timer := range r.alarms {
+			timer.Stop()
+			delete(r.alarms, key)
+		}
+	}()
+	for {
+		// Drain local events before taking the next message.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case deliver := <-r.local:
+			deliver(participant)
+			continue
+		default:
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case deliver := <-r.local:
+			deliver(participant)
+		case deliver := <-r.events:
+			deliver(participant)
+		}
+	}
+}
+
+// Receives a message from another participant, for delivery to the participant from the event loop.
+// Safe to call from any goroutine, but blocks while the event queue is full.
+// A message sent by the participant itself is dropped, since the participant receives its own messages
+// when sending them.
+func (r *Runtime) ReceiveMessage(msg *f3.GMessage) {
+	r.enqueue(r.events, messageEvent(msg))
+}
+
+// Receives a canonical chain, for delivery to the participant from the event loop ahead of any messages.
+// Safe to call from any goroutine, but blocks while the local event queue is full.
+func (r *Runtime) ReceiveCanonicalChain(chain f3.ECChain) {
+	r.enqueue(r.local, chainEvent(chain))
+}
+
+func messageEvent(msg *f3.GMessage) func(p f3.Receiver) {
+	return func(p f3.Receiver) {
+		if msg.Sender == p.ID() {
+			return
+		}
+		p.ReceiveMessage(msg)
+	}
+}
+
+func chainEvent(chain f3.ECChain) func(p f3.Receiver) {
+	return func(p f3.Receiver) {
+		p.ReceiveCanonicalChain(chain)
+	}
+}
+
+// Enqueues an event, blocking while the queue is full. The event is dropped if the event loop has stopped.
+func (r *Runtime) enqueue(queue chan func(p f3.Receiver), event func(p f3.Receiver)) {
+	select {
+	case queue <- event:
+	case <-r.stopped:
+	}
+}
+
+// Enqueues an event without blocking, returning ErrQueueFull if the queue is full,
+// or ErrStopped if the event loop has stopped.
+func (r *Runtime) tryEnqueue(queue chan func(p f3.Receiver), event func(p f3.Receiver)) error {
+	select {
+	case <-r.stopped:
+		return ErrStopped
+	default:
+	}
+	select {
+	case queue <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+///// f3.Network implementation, called by the participant from the event loop.
+
+func (r *Runtime) Broadcast(msg *f3.GMessage) {
+	if err := r.transport.Broadcast(msg); err != nil {
+		r.Log("failed to broadcast %s: %s", msg, err)
+	}
+}
+
+// Returns the number of seconds since the runtime was created, measured with a monotonic clock.
+func (r *Runtime) Time() float64 {
+	return time.Since(r.start).Seconds()
+}
+
+// Sets an alarm to fire at a runtime time, replacing any pending alarm with the same identity.
+// An alarm in the past fires immediately, but is delivered after the current event.
+func (r *Runtime) SetAlarm(sender f3.ActorID, alarm f3.Alarm, at float64) {
+	key := alarmKey{sender, alarm}
+	r.CancelAlarm(sender, alarm)
+	delay := time.Duration((at - r.Time()) * float64(time.Second))
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		r.enqueue(r.local, func(p f3.Receiver) {
+			// The timer may have fired just as it was cancelled or replaced.
+			if r.alarms[key] != timer {
+				return
+			}
+			delete(r.alarms, key)
+			p.ReceiveAlarm(alarm)
+		})
+	})
+	r.alarms[key] = timer
+}
+
+func (r *Runtime) CancelAlarm(sender f3.ActorID, alarm f3.Alarm) {
+	key := alarmKey{sender, alarm}
+	if timer, ok := r.alarms[key]; ok {
+		timer.Stop()
+		delete(r.alarms, key)
+	}
+}
+
+func (r *Runtime) Log(format string, args ...interface{}) {
+	r.logf(format, args...)
+}
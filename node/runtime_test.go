@@ -0,0 +1,161 @@
+package node
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+// A transport delivering each message to other runtimes asynchronously.
+type loopback struct {
+	runtimes []*Runtime
+}
+
+func (l *loopback) Broadcast(msg *f3.GMessage) error {
+	for _, r := range l.runtimes {
+		go r.ReceiveMessage(msg)
+	}
+	return nil
+}
+
+type nopTransport struct{}
+
+func (nopTransport) Broadcast(*f3.GMessage) error { return nil }
+
+// A receiver which sets alarms on receiving a chain, and records the alarms it receives.
+type alarmReceiver struct {
+	rt     *Runtime
+	alarms chan f3.Alarm
+}
+
+func (a *alarmReceiver) ID() f3.ActorID                  { return 0 }
+func (a *alarmReceiver) ReceiveMessage(msg *f3.GMessage) {}
+func (a *alarmReceiver) ReceiveAlarm(alarm f3.Alarm)     { a.alarms <- alarm }
+
+func (a *alarmReceiver) ReceiveCanonicalChain(f3.ECChain) {
+	now := a.rt.Time()
+	a.rt.SetAlarm(0, f3.Alarm{Round: 1}, now+0.02)
+	a.rt.SetAlarm(0, f3.Alarm{Round: 2}, now+0.01)
+	a.rt.CancelAlarm(0, f3.Alarm{Round: 2})
+	// Replacing an alarm reschedules it.
+	a.rt.SetAlarm(0, f3.Alarm{Round: 3}, now+10)
+	a.rt.SetAlarm(0, f3.Alarm{Round: 3}, now+0.03)
+	a.rt.SetAlarm(0, f3.Alarm{Round: 4}, now-1)
+}
+
+func TestAlarms(t *testing.T) {
+	rt := NewRuntime(nopTransport{})
+	receiver := &alarmReceiver{rt: rt, alarms: make(chan f3.Alarm, 10)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- rt.Run(ctx, receiver) }()
+
+	rt.ReceiveCanonicalChain(nil)
+	for _, round := range []int{4, 1, 3} {
+		select {
+		case alarm := <-receiver.alarms:
+			require.Equal(t, round, alarm.Round)
+		case <-time.After(time.Second):
+			t.Fatalf("alarm for round %d not received", round)
+		}
+	}
+	select {
+	case alarm := <-receiver.alarms:
+		t.Fatalf("unexpected %s", alarm)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	// Messages and chains received after shutdown are dropped without blocking.
+	for i := 0; i < eventQueueLength+1; i++ {
+		rt.ReceiveCanonicalChain(nil)
+	}
+}
+
+// A receiver recording the kind of each event it receives.
+type eventRecorder struct {
+	events chan string
+}
+
+func (e *eventRecorder) ID() f3.ActorID                   { return 0 }
+func (e *eventRecorder) ReceiveMessage(*f3.GMessage)      { e.events <- "message" }
+func (e *eventRecorder) ReceiveCanonicalChain(f3.ECChain) { e.events <- "chain" }
+func (e *eventRecorder) ReceiveAlarm(f3.Alarm)            { e.events <- "alarm" }
+
+func TestLocalEventsTakePriority(t *testing.T) {
+	rt := NewRuntime(nopTransport{})
+	receiver := &eventRecorder{events: make(chan string, eventQueueLength+2)}
+	for i := 0; i < eventQueueLength; i++ {
+		rt.ReceiveMessage(&f3.GMessage{Sender: 1})
+	}
+	rt.ReceiveCanonicalChain(nil)
+	rt.SetAlarm(0, f3.Alarm{}, rt.Time()-1)
+	// Wait for the alarm's timer to enqueue it.
+	require.Eventually(t, func() bool { return len(rt.local) == 2 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- rt.Run(ctx, receiver) }()
+	// The chain and alarm are delivered before any of the messages queued ahead of them.
+	require.Equal(t, "chain", <-receiver.events)
+	require.Equal(t, "alarm", <-receiver.events)
+	for i := 0; i < eventQueueLength; i++ {
+		require.Equal(t, "message", <-receiver.events)
+	}
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestTime(t *testing.T) {
+	rt := NewRuntime(nopTransport{})
+	before := rt.Time()
+	time.Sleep(10 * time.Millisecond)
+	require.GreaterOrEqual(t, rt.Time()-before, 0.01)
+}
+
+func TestParticipantsDecide(t *testing.T) {
+	const count = 4
+	config := f3.GraniteConfig{Delta: 0.05, DeltaRate: 0.05}
+	signer := sim.NewFakeSigner()
+	powerTables := sim.NewPowerTables()
+	power := f3.NewPowerTable()
+	transport := &loopback{}
+	participants := make([]*f3.Participant, count)
+	decisions := make(chan *f3.FinalityCertificate, count)
+	for i := range participants {
+		id := f3.ActorID(i)
+		rt := NewRuntime(transport)
+		transport.runtimes = append(transport.runtimes, rt)
+		p, err := f3.NewParticipant(id, config, rt, f3.NewFakeVRF(), signer, powerTables, sim.NewBeacons())
+		require.NoError(t, err)
+		participants[i] = p
+		participants[i].OnDecision(func(cert *f3.FinalityCertificate) {
+			decisions <- cert
+		})
+		power.Add(id, big.NewInt(1), sim.FakePubKey(id))
+	}
+	powerTables.Set(0, power)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	base := f3.NewChain(f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1))
+	chain := base.Extend(f3.CIDOf([]byte("a")))
+	for i, rt := range transport.runtimes {
+		go func(rt *Runtime, p *f3.Participant) { _ = rt.Run(ctx, p) }(rt, participants[i])
+		rt.ReceiveCanonicalChain(chain)
+	}
+	for range participants {
+		select {
+		case cert := <-decisions:
+			require.Equal(t, *chain.Head(), *cert.Head())
+		case <-time.After(10 * time.Second):
+			t.Fatal("participants did not decide")
+		}
+	}
+}
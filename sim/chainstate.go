@@ -0,0 +1,130 @@
+package sim
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// An in-memory power table provider.
+// Power tables are set from some epoch onwards, and the power table for a chain state
+// is the one set at the latest epoch not after it.
+type PowerTables struct {
+	// Epochs at which power tables were set, ascending.
+	epochs []int
+	tables map[int]f3.PowerTable
+}
+
+func NewPowerTables() *PowerTables {
+	return &PowerTables{
+		tables: map[int]f3.PowerTable{},
+	}
+}
+
+// Sets the power table from some epoch onwards, replacing any table set at the same epoch.
+func (p *PowerTables) Set(epoch int, table f3.PowerTable) {
+	if _, ok := p.tables[epoch]; !ok {
+		p.epochs = append(p.epochs, epoch)
+		sort.Ints(p.epochs)
+	}
+	p.tables[epoch] = table
+}
+
+func (p *PowerTables) GetPowerTable(base f3.TipSet, lookback int) (f3.PowerTable, error) {
+	epoch := base.Epoch - lookback
+	// Find the index of the first epoch after the target.
+	i := sort.Search(len(p.epochs), func(i int) bool {
+		return p.epochs[i] > epoch
+	})
+	if i == 0 {
+		return f3.PowerTable{}, fmt.Errorf("no power table at epoch %d", epoch)
+	}
+	return p.tables[p.epochs[i-1]], nil
+}
+
+// A beacon provider deriving a beacon value from each base tipset's CID.
+type Beacons struct {
+}
+
+func NewBeacons() *Beacons {
+	return &Beacons{}
+}
+
+func (b *Beacons) GetBeacon(base f3.TipSet) ([]byte, error) {
+	digest := sha256.Sum256(append([]byte("beacon"), base.CID.Bytes()...))
+	return digest[:], nil
+}
+
+// An in-memory chain store, populated with the chains a participant has received.
+type ChainStore struct {
+	tipsets map[f3.CID]f3.TipSet
+	// Parent of each tipset added with its parent.
+	parents map[f3.CID]f3.CID
+	// CID of the tipset at each epoch in the heaviest chain.
+	heaviest map[int]f3.CID
+}
+
+func NewChainStore() *ChainStore {
+	return &ChainStore{
+		tipsets:  map[f3.CID]f3.TipSet{},
+		parents:  map[f3.CID]f3.CID{},
+		heaviest: map[int]f3.CID{},
+	}
+}
+
+// Adds a chain's tipsets to the store, each the parent of the next.
+func (c *ChainStore) Add(chain f3.ECChain) {
+	for i, ts := range chain {
+		c.tipsets[ts.CID] = ts
+		if i > 0 {
+			c.parents[ts.CID] = chain[i-1].CID
+		}
+	}
+}
+
+// Adds a chain to the store and makes it the heaviest chain from its base onwards.
+// Epochs after the chain's head, and those of null rounds within it, no longer have a tipset.
+func (c *ChainStore) SetHeaviest(chain f3.ECChain) {
+	c.Add(chain)
+	for epoch := range c.heaviest {
+		if epoch > chain.Base().Epoch {
+			delete(c.heaviest, epoch)
+		}
+	}
+	for _, ts := range chain {
+		c.heaviest[ts.Epoch] = ts.CID
+	}
+}
+
+func (c *ChainStore) GetTipSet(cid f3.CID) (f3.TipSet, error) {
+	ts, ok := c.tipsets[cid]
+	if !ok {
+		return f3.TipSet{}, fmt.Errorf("tipset %s not found", cid)
+	}
+	return ts, nil
+}
+
+func (c *ChainStore) GetTipSetByEpoch(epoch int) (f3.TipSet, error) {
+	cid, ok := c.heaviest[epoch]
+	if !ok {
+		return f3.TipSet{}, fmt.Errorf("no tipset at epoch %d", epoch)
+	}
+	return c.tipsets[cid], nil
+}
+
+func (c *ChainStore) IsAncestor(ancestor f3.CID, descendant f3.CID) (bool, error) {
+	if _, ok := c.tipsets[ancestor]; !ok {
+		return false, fmt.Errorf("tipset %s not found", ancestor)
+	}
+	if _, ok := c.tipsets[descendant]; !ok {
+		return false, fmt.Errorf("tipset %s not found", descendant)
+	}
+	for cid, ok := c.parents[descendant]; ok; cid, ok = c.parents[cid] {
+		if cid == ancestor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
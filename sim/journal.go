@@ -0,0 +1,67 @@
+package sim
+
+import (
+	"errors"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// Panic value unwinding the call stack of a participant which crashes while handling an event,
+// recovered by the simulation delivering the event.
+var errCrashed = errors.New("participant crashed")
+
+// An in-memory journal, which can inject a crash of its participant immediately after an append.
+type Journal struct {
+	entries []*f3.JournalEntry
+	// Number of appends remaining before the crash, if one is scheduled.
+	crashAfter int
+	onCrash    func()
+	// Whether the participant has crashed, after which the journal refuses to change.
+	crashed bool
+}
+
+func NewJournal() *Journal {
+	return &Journal{}
+}
+
+// Schedules a crash, invoking a callback immediately after some number of subsequent appends.
+// The crash happens after the last entry is durable, but before the participant acts on it:
+// after the callback, the participant's call stack is unwound with a panic which the simulation recovers.
+// The journal refuses any subsequent append or rewrite.
+func (j *Journal) CrashAfter(appends int, onCrash func()) {
+	j.crashAfter = appends
+	j.onCrash = onCrash
+}
+
+// Returns a new journal with the same entries, as a restarted participant finds its journal.
+func (j *Journal) Copy() *Journal {
+	return &Journal{entries: append([]*f3.JournalEntry{}, j.entries...)}
+}
+
+func (j *Journal) Append(entry *f3.JournalEntry) error {
+	if j.crashed {
+		return errCrashed
+	}
+	j.entries = append(j.entries, entry)
+	if j.onCrash != nil {
+		j.crashAfter -= 1
+		if j.crashAfter == 0 {
+			j.crashed = true
+			j.onCrash()
+			panic(errCrashed)
+		}
+	}
+	return nil
+}
+
+func (j *Journal) Entries() ([]*f3.JournalEntry, error) {
+	return append([]*f3.JournalEntry{}, j.entries...), nil
+}
+
+func (j *Journal) Rewrite(entries []*f3.JournalEntry) error {
+	if j.crashed {
+		return errCrashed
+	}
+	j.entries = append([]*f3.JournalEntry{}, entries...)
+	return nil
+}
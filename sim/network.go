@@ -3,8 +3,8 @@ package sim
 import (
 	"fmt"
 	"github.com/filecoin-project/go-f3/f3"
+	"math/rand"
 	"sort"
-	"strings"
 )
 
 type AdversaryReceiver interface {
@@ -37,6 +37,11 @@ type Network struct {
 	// Messages received by the network but not yet delivered to all participants.
 	queue   messageQueue
 	latency LatencyModel
+	// Probability of dropping each broadcast message to each receiver, and the source of randomness for it.
+	lossRate float64
+	lossRng  *rand.Rand
+	// Participants which have crashed, to and from which nothing is delivered until they restart.
+	crashed map[f3.ActorID]bool
 	// Timestamp of last event.
 	clock float64
 	// Whether global stabilisation time has passed, so adversary can't control network.
@@ -50,6 +55,7 @@ func NewNetwork(latency LatencyModel, traceLevel int) *Network {
 		participants:               map[f3.ActorID]f3.Receiver{},
 		participantIDs:             []f3.ActorID{},
 		queue:                      messageQueue{},
+		crashed:                    map[f3.ActorID]bool{},
 		clock:                      0,
 		latency:                    latency,
 		globalStabilisationElapsed: false,
@@ -65,10 +71,53 @@ func (n *Network) AddParticipant(p f3.Receiver) {
 	n.participants[p.ID()] = p
 }
 
+// Crashes a participant: its subsequent messages and alarms are dropped, along with any pending alarms,
+// and nothing is delivered to it until it restarts.
+func (n *Network) Crash(id f3.ActorID) {
+	n.log(TraceSent, "P%d 💥 crashed", id)
+	n.crashed[id] = true
+	for i := 0; i < len(n.queue); {
+		if _, ok := n.queue[i].payload.(f3.Alarm); ok && n.queue[i].dest == id {
+			n.queue.Remove(i)
+		} else {
+			i++
+		}
+	}
+}
+
+// Checks whether a participant has crashed and not yet restarted.
+func (n *Network) Crashed(id f3.ActorID) bool {
+	return n.crashed[id]
+}
+
+// Replaces a crashed participant with a restarted one with the same ID.
+func (n *Network) Restart(p f3.Receiver) {
+	if n.participants[p.ID()] == nil {
+		panic("unknown participant ID")
+	}
+	n.log(TraceSent, "P%d restarted", p.ID())
+	delete(n.crashed, p.ID())
+	n.participants[p.ID()] = p
+}
+
+// Configures the network to drop each broadcast message to each receiver with some probability.
+// Alarms and adversary messages are never dropped.
+func (n *Network) SetLoss(seed int64, rate float64) {
+	n.lossRate = rate
+	n.lossRng = rand.New(rand.NewSource(seed))
+}
+
 func (n *Network) Broadcast(msg *f3.GMessage) {
+	if n.crashed[msg.Sender] {
+		return
+	}
 	n.log(TraceSent, "P%d ↗ %v", msg.Sender, msg)
 	for _, k := range n.participantIDs {
 		if k != msg.Sender {
+			if n.lossRate > 0 && n.lossRng.Float64() < n.lossRate {
+				n.log(TraceSent, "P%d ↛ P%d: %v", msg.Sender, k, msg)
+				continue
+			}
 			latency := n.latency.Sample()
 			n.queue.Insert(
 				messageInFlight{
@@ -85,15 +134,27 @@ func (n *Network) Time() float64 {
 	return n.clock
 }
 
-func (n *Network) SetAlarm(sender f3.ActorID, payload string, at float64) {
+func (n *Network) SetAlarm(sender f3.ActorID, alarm f3.Alarm, at float64) {
+	if n.crashed[sender] {
+		return
+	}
 	n.queue.Insert(messageInFlight{
 		source:    sender,
 		dest:      sender,
-		payload:   "ALARM:" + payload,
+		payload:   alarm,
 		deliverAt: at,
 	})
 }
 
+func (n *Network) CancelAlarm(sender f3.ActorID, alarm f3.Alarm) {
+	for i, msg := range n.queue {
+		if msg.dest == sender && msg.payload == alarm {
+			n.queue.Remove(i)
+			return
+		}
+	}
+}
+
 func (n *Network) Log(format string, args ...interface{}) {
 	n.log(TraceLogic, format, args...)
 }
@@ -135,16 +196,33 @@ func (n *Network) Tick(adv AdversaryReceiver) bool {
 
 	msg := n.queue.Remove(i)
 	n.clock = msg.deliverAt
-	payloadStr, ok := msg.payload.(string)
-	if ok && strings.HasPrefix(payloadStr, "ALARM:") {
-		n.log(TraceRecvd, "P%d %s", msg.source, payloadStr)
-		n.participants[msg.dest].ReceiveAlarm(strings.TrimPrefix(payloadStr, "ALARM:"))
+	n.deliver(msg)
+	return len(n.queue) > 0
+}
+
+// Delivers a message or alarm to its destination, unless the destination has crashed.
+// The destination may crash while handling it.
+func (n *Network) deliver(msg messageInFlight) {
+	defer recoverCrash()
+	alarm, ok := msg.payload.(f3.Alarm)
+	if n.crashed[msg.dest] {
+		n.log(TraceRecvd, "P%d ↛ P%d: %v", msg.source, msg.dest, msg.payload)
+	} else if ok {
+		n.log(TraceRecvd, "P%d %s", msg.source, alarm)
+		n.participants[msg.dest].ReceiveAlarm(alarm)
 	} else {
 		n.log(TraceRecvd, "P%d ← P%d: %v", msg.dest, msg.source, msg.payload)
 		gmsg := msg.payload.(f3.GMessage)
 		n.participants[msg.dest].ReceiveMessage(&gmsg)
 	}
-	return len(n.queue) > 0
+}
+
+// Recovers from the panic unwinding a participant which crashed, re-panicking with anything else.
+// Must be deferred directly.
+func recoverCrash() {
+	if r := recover(); r != nil && r != errCrashed {
+		panic(r)
+	}
 }
 
 func (n *Network) log(level int, format string, args ...interface{}) {
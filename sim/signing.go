@@ -0,0 +1,55 @@
+package sim
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// A deterministic signer for simulations.
+// A fake signature is a digest of the public key and payload, so anyone can forge one.
+type FakeSigner struct {
+}
+
+func NewFakeSigner() *FakeSigner {
+	return &FakeSigner{}
+}
+
+// Returns the fake public key for a participant.
+func FakePubKey(id f3.ActorID) f3.PubKey {
+	return []byte(fmt.Sprintf("FakePubKey(%d)", id))
+}
+
+func (s *FakeSigner) Sign(sender f3.PubKey, msg []byte) []byte {
+	digest := sha256.New()
+	digest.Write(sender)
+	digest.Write(msg)
+	return digest.Sum(nil)
+}
+
+func (s *FakeSigner) Verify(pubKey f3.PubKey, msg []byte, sig []byte) bool {
+	return bytes.Equal(s.Sign(pubKey, msg), sig)
+}
+
+// Aggregates fake signatures by digesting their concatenation.
+func (s *FakeSigner) Aggregate(pubKeys []f3.PubKey, sigs [][]byte) ([]byte, error) {
+	if len(pubKeys) != len(sigs) {
+		return nil, fmt.Errorf("%d public keys but %d signatures", len(pubKeys), len(sigs))
+	}
+	digest := sha256.New()
+	for _, sig := range sigs {
+		digest.Write(sig)
+	}
+	return digest.Sum(nil), nil
+}
+
+func (s *FakeSigner) VerifyAggregate(payload []byte, aggSig []byte, pubKeys []f3.PubKey) bool {
+	sigs := make([][]byte, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		sigs[i] = s.Sign(pubKey, payload)
+	}
+	expected, err := s.Aggregate(pubKeys, sigs)
+	return err == nil && bytes.Equal(expected, aggSig)
+}
@@ -3,6 +3,7 @@ package sim
 import (
 	"fmt"
 	"github.com/filecoin-project/go-f3/f3"
+	"math/big"
 	"strings"
 )
 
@@ -12,16 +13,31 @@ type Config struct {
 	HonestCount int
 	LatencySeed int64
 	LatencyMean float64
+	// Probability of losing each message to each receiver, seeded by LatencySeed.
+	LossRate float64
+	// VRF with which participants produce and verify tickets. If nil, tickets are fake.
+	VRF f3.VRFer
 }
 
 type Simulation struct {
 	Network      *Network
 	Base         f3.ECChain
 	PowerTable   f3.PowerTable
-	Beacon       []byte
+	PowerTables  *PowerTables
+	Beacons      *Beacons
+	Signer       *FakeSigner
 	Participants []*f3.Participant
-	Adversary    AdversaryReceiver
-	CIDGen       *CIDGen
+	// Each honest participant's local chain store, in the same order as Participants.
+	ChainStores []*ChainStore
+	// Each honest participant's journal, in the same order as Participants.
+	Journals  []*Journal
+	Adversary AdversaryReceiver
+	CIDGen    *CIDGen
+	// Configuration and dependencies from which participants are (re)created.
+	graniteConfig f3.GraniteConfig
+	vrf           f3.VRFer
+	// Certificates of each honest participant's decisions, by participant ID and instance.
+	decisions map[f3.ActorID]map[int]*f3.FinalityCertificate
 }
 
 type AdversaryFactory func(id string, ntwk f3.Network) f3.Receiver
@@ -30,35 +46,108 @@ func NewSimulation(simConfig Config, graniteConfig f3.GraniteConfig, traceLevel
 	// Create a network to deliver messages.
 	lat := NewLogNormal(simConfig.LatencySeed, simConfig.LatencyMean)
 	ntwk := NewNetwork(lat, traceLevel)
-	vrf := f3.NewFakeVRF()
-
-	// Create participants.
-	genesisPower := f3.NewPowerTable()
-	participants := make([]*f3.Participant, simConfig.HonestCount)
-	for i := 0; i < len(participants); i++ {
-		participants[i] = f3.NewParticipant(f3.ActorID(i), graniteConfig, ntwk, vrf)
-		ntwk.AddParticipant(participants[i])
-		genesisPower.Add(participants[i].ID(), 1)
+	if simConfig.LossRate > 0 {
+		ntwk.SetLoss(simConfig.LatencySeed, simConfig.LossRate)
+	}
+	var vrf f3.VRFer = f3.NewFakeVRF()
+	if simConfig.VRF != nil {
+		vrf = simConfig.VRF
 	}
+	signer := NewFakeSigner()
+	powerTables := NewPowerTables()
+	beacons := NewBeacons()
 
 	// Create genesis tipset, which all participants are expected to agree on as a base.
-	genesis := f3.NewTipSet(100, "genesis", 1)
+	genesis := f3.NewTipSet(100, f3.CIDOf([]byte("genesis")), 1)
 	baseChain := f3.NewChain(genesis)
-	return &Simulation{
-		Network:      ntwk,
-		Base:         baseChain,
-		PowerTable:   genesisPower,
-		Beacon:       []byte("beacon"),
-		Participants: participants,
-		Adversary:    nil,
-		CIDGen:       NewCIDGen(0x264803e715714f95), // Seed from Drand
+	s := &Simulation{
+		Network:       ntwk,
+		Base:          baseChain,
+		PowerTables:   powerTables,
+		Beacons:       beacons,
+		Signer:        signer,
+		Participants:  make([]*f3.Participant, simConfig.HonestCount),
+		ChainStores:   make([]*ChainStore, simConfig.HonestCount),
+		Journals:      make([]*Journal, simConfig.HonestCount),
+		Adversary:     nil,
+		CIDGen:        NewCIDGen(0x264803e715714f95), // Seed from Drand
+		graniteConfig: graniteConfig,
+		vrf:           vrf,
+		decisions:     map[f3.ActorID]map[int]*f3.FinalityCertificate{},
+	}
+
+	// Create participants, recording their decisions.
+	genesisPower := f3.NewPowerTable()
+	for i := 0; i < simConfig.HonestCount; i++ {
+		id := f3.ActorID(i)
+		s.ChainStores[i] = NewChainStore()
+		s.Journals[i] = NewJournal()
+		s.Participants[i] = s.newParticipant(i)
+		ntwk.AddParticipant(s.Participants[i])
+		s.restore(i)
+		genesisPower.Add(id, big.NewInt(1), FakePubKey(id))
+		s.decisions[id] = map[int]*f3.FinalityCertificate{}
+	}
+	s.PowerTable = genesisPower
+	powerTables.Set(0, genesisPower)
+	return s
+}
+
+// Creates the honest participant at some index, with its chain store, recording its decisions.
+func (s *Simulation) newParticipant(i int) *f3.Participant {
+	id := f3.ActorID(i)
+	p, err := f3.NewParticipant(id, s.graniteConfig, s.Network, s.vrf, s.Signer, s.PowerTables, s.Beacons)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create P%d: %s", i, err))
 	}
+	p.SetChainStore(s.ChainStores[i])
+	p.OnDecision(func(cert *f3.FinalityCertificate) {
+		s.decisions[id][cert.Instance] = cert
+	})
+	// An honest participant attempting to equivocate is a bug, which must fail the simulation.
+	p.OnSelfEquivocation(func(err error) {
+		panic(fmt.Sprintf("P%d: %s", i, err))
+	})
+	return p
 }
 
-func (s *Simulation) SetAdversary(adv AdversaryReceiver, power uint) {
+// Sets the journal of the honest participant at some index, restoring any state already journaled.
+// The participant must be connected to the network, since it resumes by re-sending its latest message.
+func (s *Simulation) restore(i int) {
+	if err := s.Participants[i].SetJournal(s.Journals[i]); err != nil {
+		panic(fmt.Sprintf("failed to restore P%d from journal: %s", i, err))
+	}
+}
+
+// Schedules an honest participant to crash immediately after some number of further journal appends,
+// i.e. after journaling some state but before acting on it.
+// A crashed participant neither sends nor receives anything until it is restarted,
+// when it is restored from its journal as of the crash.
+func (s *Simulation) CrashAfter(i int, appends int) {
+	id := s.Participants[i].ID()
+	journal := s.Journals[i]
+	journal.CrashAfter(appends, func() {
+		s.Journals[i] = journal.Copy()
+		s.Network.Crash(id)
+	})
+}
+
+// Restarts a crashed honest participant as a new participant restored from its journal.
+// The restarted participant has the chain store of the one it replaces, but must receive a chain
+// to begin any instance after those journaled.
+func (s *Simulation) Restart(i int) *f3.Participant {
+	p := s.newParticipant(i)
+	s.Participants[i] = p
+	s.Network.Restart(p)
+	s.restore(i)
+	return p
+}
+
+func (s *Simulation) SetAdversary(adv AdversaryReceiver, power int64) {
 	s.Adversary = adv
 	s.Network.AddParticipant(adv)
-	s.PowerTable.Add(adv.ID(), power)
+	s.PowerTable.Add(adv.ID(), big.NewInt(power), FakePubKey(adv.ID()))
+	s.PowerTables.Set(0, s.PowerTable)
 }
 
 type ChainCount struct {
@@ -67,11 +156,14 @@ type ChainCount struct {
 }
 
 // Delivers canonical chains to honest participants.
+// Participants not running an instance begin the next instance with the chain,
+// while those running an instance use it as input for the next one.
 func (s *Simulation) ReceiveChains(chains ...ChainCount) {
 	pidx := 0
 	for _, chain := range chains {
 		for i := 0; i < chain.Count; i++ {
-			s.Participants[pidx].ReceiveCanonicalChain(chain.Chain, s.PowerTable, s.Beacon)
+			s.ChainStores[pidx].SetHeaviest(chain.Chain)
+			s.receiveChain(pidx, chain.Chain)
 			pidx += 1
 		}
 	}
@@ -80,38 +172,90 @@ func (s *Simulation) ReceiveChains(chains ...ChainCount) {
 	}
 }
 
-// Runs simulation, and returns whether all participants decided on the same value.
+// Delivers a canonical chain to an honest participant unless it has crashed.
+// The participant may crash while handling it.
+func (s *Simulation) receiveChain(i int, chain f3.ECChain) {
+	defer recoverCrash()
+	if !s.Network.Crashed(s.Participants[i].ID()) {
+		s.Participants[i].ReceiveCanonicalChain(chain)
+	}
+}
+
+// Runs simulation of the first instance, and returns whether all participants decided on the same value.
 func (s *Simulation) Run(maxRounds int) bool {
-	// Run until there are no more messages, meaning termination or deadlock.
-	for s.Network.Tick(s.Adversary) && s.Participants[0].CurrentRound() <= maxRounds {
+	return s.RunInstances(1, maxRounds)
+}
+
+// Runs simulation until all participants have decided some number of instances,
+// and returns whether all participants decided on the same value in each instance.
+// A participant which caught up to a later instance's decision may have skipped earlier instances,
+// so need not have decided every instance, but all participants must decide the last instance.
+func (s *Simulation) RunInstances(instanceCount int, maxRounds int) bool {
+	lastInstance := instanceCount - 1
+	// Run until all participants decide, or there are no more messages, meaning deadlock.
+	for !s.allDecided(lastInstance) && s.Network.Tick(s.Adversary) && s.Participants[0].CurrentRound() <= maxRounds {
 	}
-	if s.Participants[0].CurrentRound() >= maxRounds {
+	if s.Participants[0].CurrentRound() >= maxRounds || !s.allDecided(lastInstance) {
 		return false
 	}
-	first, _ := s.Participants[0].Finalised()
-	for _, p := range s.Participants {
-		f, _ := p.Finalised()
-		if f.Eq(&f3.TipSet{}) {
+	for instance := 0; instance <= lastInstance; instance++ {
+		first := s.Decided(instance)
+		if first == nil {
 			return false
 		}
-		if !f.Eq(&first) {
+		for _, p := range s.Participants {
+			cert := s.Decision(p.ID(), instance)
+			if cert != nil && !cert.Head().Eq(first.Head()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns the certificate of a participant's decision in some instance, or nil if it has not decided.
+func (s *Simulation) Decision(id f3.ActorID, instance int) *f3.FinalityCertificate {
+	return s.decisions[id][instance]
+}
+
+// Returns the certificate of the first participant to have decided some instance, or nil if none has.
+func (s *Simulation) Decided(instance int) *f3.FinalityCertificate {
+	for _, p := range s.Participants {
+		if cert := s.Decision(p.ID(), instance); cert != nil {
+			return cert
+		}
+	}
+	return nil
+}
+
+// Checks whether all participants have decided some instance.
+func (s *Simulation) allDecided(instance int) bool {
+	for _, p := range s.Participants {
+		if s.Decision(p.ID(), instance) == nil {
 			return false
 		}
 	}
 	return true
 }
 
+// Prints any disagreement or missing decisions in each instance which some participant decided.
 func (s *Simulation) PrintResults() {
-	var firstFin f3.TipSet
-	for _, p := range s.Participants {
-		thisFin, _ := p.Finalised()
-		if firstFin.Eq(&f3.TipSet{}) {
-			firstFin = thisFin
+	for instance := 0; ; instance++ {
+		first := s.Decided(instance)
+		if first == nil {
+			if instance == 0 {
+				fmt.Printf("‼️ No participant decided\n")
+			}
+			return
 		}
-		if thisFin.Eq(&f3.TipSet{}) {
-			fmt.Printf("‼️ Participant %d did not decide\n", p.ID())
-		} else if !thisFin.Eq(&firstFin) {
-			fmt.Printf("‼️ Participant %d decided %v, but %d decided %v\n", p.ID(), thisFin, s.Participants[0].ID(), firstFin)
+		for _, p := range s.Participants {
+			cert := s.Decision(p.ID(), instance)
+			if cert == nil {
+				fmt.Printf("‼️ Participant %d did not decide instance %d\n", p.ID(), instance)
+			} else if !cert.Head().Eq(first.Head()) {
+				fmt.Printf("‼️ Participant %d decided %v in instance %d, but another decided %v\n",
+					p.ID(), cert.Head(), instance, first.Head())
+			}
 		}
 	}
 }
@@ -126,7 +270,7 @@ func (s *Simulation) Describe() string {
 }
 
 // A CID generator.
-// This uses a fast xorshift PRNG to generate random CIDs.
+// This uses a fast xorshift PRNG to generate random content, and returns the CID of that content.
 // The statistical properties of these CIDs are not important to correctness.
 type CIDGen struct {
 	xorshiftState uint64
@@ -141,7 +285,7 @@ func (c *CIDGen) Sample() f3.CID {
 	for i := range b {
 		b[i] = alphanum[c.nextN(len(alphanum))]
 	}
-	return string(b)
+	return f3.CIDOf([]byte(string(b)))
 }
 
 func (c *CIDGen) nextN(n int) uint64 {
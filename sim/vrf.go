@@ -0,0 +1,39 @@
+package sim
+
+import (
+	"github.com/filecoin-project/go-f3/blssig"
+	"github.com/filecoin-project/go-f3/f3"
+)
+
+// A VRF producing real BLS tickets in a simulation whose power table holds fake public keys.
+// Each fake key is mapped to a BLS key derived from it, so a ticket is the unique BLS signature
+// by the participant's key over the VRF payload, and verifies for no other participant.
+type BLSVRF struct {
+	signer *blssig.Signer
+	vrf    *f3.SignatureVRF
+	// BLS public key for each fake public key.
+	keys map[string]f3.PubKey
+}
+
+func NewBLSVRF() *BLSVRF {
+	signer := blssig.NewSigner()
+	return &BLSVRF{signer: signer, vrf: blssig.NewVRF(signer), keys: map[string]f3.PubKey{}}
+}
+
+func (v *BLSVRF) MakeTicket(beacon []byte, instance int, round int, signer f3.PubKey) f3.Ticket {
+	return v.vrf.MakeTicket(beacon, instance, round, v.key(signer))
+}
+
+func (v *BLSVRF) VerifyTicket(beacon []byte, instance int, round int, signer f3.PubKey, ticket f3.Ticket) bool {
+	return v.vrf.VerifyTicket(beacon, instance, round, v.key(signer), ticket)
+}
+
+// Returns the BLS public key for a fake public key, deriving the key pair if not already known.
+func (v *BLSVRF) key(fake f3.PubKey) f3.PubKey {
+	key, ok := v.keys[string(fake)]
+	if !ok {
+		key = v.signer.AddKey(blssig.KeyFromSeed(fake))
+		v.keys[string(fake)] = key
+	}
+	return key
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatchUpFromDecide(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := a.Extend(sm.CIDGen.Sample())
+	// The last participant never receives a canonical chain, so never runs an instance,
+	// but finalises each instance from the others' DECIDE messages.
+	laggard := sm.Participants[3]
+	for _, chain := range []f3.ECChain{a, b} {
+		for _, p := range sm.Participants[:3] {
+			p.ReceiveCanonicalChain(chain)
+		}
+	}
+
+	require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+	require.Equal(t, -1, laggard.CurrentRound())
+	finalised, _ := laggard.Finalised()
+	require.Equal(t, *b.Head(), finalised)
+}
+
+func TestCatchUpFromCertificate(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := a.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+	require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+	first := sm.Decided(0)
+	second := sm.Decided(1)
+
+	// A restarted participant, outside the simulated network, resumes from the latest certificate.
+	restarted, err := f3.NewParticipant(0, GraniteConfig(), sm.Network, f3.NewFakeVRF(), sm.Signer, sm.PowerTables,
+		sm.Beacons)
+	require.NoError(t, err)
+	var decided []*f3.FinalityCertificate
+	restarted.OnDecision(func(cert *f3.FinalityCertificate) {
+		decided = append(decided, cert)
+	})
+
+	forged := *second
+	forged.Signature = []byte("forged")
+	require.Error(t, restarted.ReceiveFinalityCertificate(&forged))
+	require.Nil(t, restarted.FinalityCertificate())
+
+	require.NoError(t, restarted.ReceiveFinalityCertificate(second))
+	require.Equal(t, second, restarted.FinalityCertificate())
+	finalised, round := restarted.Finalised()
+	require.Equal(t, *b.Head(), finalised)
+	require.Equal(t, second.Round, round)
+	require.Equal(t, []*f3.FinalityCertificate{second}, decided)
+
+	// A certificate for an earlier instance is ignored.
+	require.NoError(t, restarted.ReceiveFinalityCertificate(first))
+	require.Equal(t, second, restarted.FinalityCertificate())
+
+	// The restarted participant joins the next instance once it has a chain extending the finalised tipset.
+	restarted.ReceiveCanonicalChain(b.Extend(sm.CIDGen.Sample()))
+	require.Equal(t, 0, restarted.CurrentRound())
+}
+
+func TestCatchUpRequiresLinkedCertificate(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := a.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+	require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+
+	// The same participants, with the same power, decide a fork in another simulation.
+	fork := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	fork.CIDGen.Sample()
+	forkA := fork.Base.Extend(fork.CIDGen.Sample())
+	forkB := forkA.Extend(fork.CIDGen.Sample())
+	fork.ReceiveChains(sim.ChainCount{Count: len(fork.Participants), Chain: forkA})
+	fork.ReceiveChains(sim.ChainCount{Count: len(fork.Participants), Chain: forkB})
+	require.True(t, fork.RunInstances(2, MAX_ROUNDS), "%s", fork.Describe())
+
+	restarted, err := f3.NewParticipant(0, GraniteConfig(), sm.Network, f3.NewFakeVRF(), sm.Signer, sm.PowerTables,
+		sm.Beacons)
+	require.NoError(t, err)
+	require.NoError(t, restarted.ReceiveFinalityCertificate(sm.Decided(0)))
+
+	// A validly signed certificate which doesn't extend the finalised tipset is rejected.
+	require.Error(t, restarted.ReceiveFinalityCertificate(fork.Decided(1)))
+	require.Equal(t, sm.Decided(0), restarted.FinalityCertificate())
+
+	require.NoError(t, restarted.ReceiveFinalityCertificate(sm.Decided(1)))
+	finalised, _ := restarted.Finalised()
+	require.Equal(t, *b.Head(), finalised)
+}
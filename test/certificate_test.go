@@ -0,0 +1,44 @@
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateMatchesDecision(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	var emitted []*f3.FinalityCertificate
+	for _, p := range sm.Participants {
+		p.OnDecision(func(cert *f3.FinalityCertificate) {
+			emitted = append(emitted, cert)
+		})
+	}
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+
+	require.True(t, sm.Run(MAX_ROUNDS), "%s", sm.Describe())
+	require.Len(t, emitted, len(sm.Participants))
+	for _, p := range sm.Participants {
+		decision, round := p.Finalised()
+		cert := p.FinalityCertificate()
+		require.NotNil(t, cert)
+		require.Equal(t, 0, cert.Instance)
+		require.Equal(t, round, cert.Round)
+		require.Equal(t, decision, *cert.Head())
+		// The signers must comprise a strong quorum, and the aggregate signature must verify.
+		require.True(t, f3.IsStrongQuorum(cert.SignersPower, sm.PowerTable.Total))
+		require.NoError(t, cert.Verify(sm.PowerTable, sm.Signer))
+		signers, err := cert.SignerIDs(sm.PowerTable)
+		require.NoError(t, err)
+		power := new(big.Int)
+		for _, s := range signers {
+			senderPower, _ := sm.PowerTable.Get(s)
+			power.Add(power, senderPower)
+		}
+		require.Zero(t, cert.SignersPower.Cmp(power))
+	}
+}
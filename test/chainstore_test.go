@@ -0,0 +1,137 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainStore(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(1), GraniteConfig(), sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := a.Extend(sm.CIDGen.Sample())
+	fork := a.Extend(sm.CIDGen.Sample())
+	store := sim.NewChainStore()
+	store.SetHeaviest(b)
+	store.Add(fork)
+
+	ts, err := store.GetTipSet(fork.Head().CID)
+	require.NoError(t, err)
+	require.Equal(t, *fork.Head(), ts)
+	_, err = store.GetTipSet(sm.CIDGen.Sample())
+	require.Error(t, err)
+
+	ts, err = store.GetTipSetByEpoch(b.Head().Epoch)
+	require.NoError(t, err)
+	require.Equal(t, *b.Head(), ts)
+	_, err = store.GetTipSetByEpoch(b.Head().Epoch + 1)
+	require.Error(t, err)
+
+	isAncestor := func(ancestor, descendant f3.CID) bool {
+		ok, err := store.IsAncestor(ancestor, descendant)
+		require.NoError(t, err)
+		return ok
+	}
+	require.True(t, isAncestor(sm.Base.Head().CID, b.Head().CID))
+	require.True(t, isAncestor(a.Head().CID, fork.Head().CID))
+	require.False(t, isAncestor(b.Head().CID, a.Head().CID))
+	require.False(t, isAncestor(fork.Head().CID, b.Head().CID))
+	require.False(t, isAncestor(b.Head().CID, b.Head().CID))
+	_, err = store.IsAncestor(sm.CIDGen.Sample(), b.Head().CID)
+	require.Error(t, err)
+
+	// A new heaviest chain replaces the previous one after its base.
+	store.SetHeaviest(fork)
+	ts, err = store.GetTipSetByEpoch(fork.Head().Epoch)
+	require.NoError(t, err)
+	require.Equal(t, *fork.Head(), ts)
+	ts, err = store.GetTipSetByEpoch(a.Head().Epoch)
+	require.NoError(t, err)
+	require.Equal(t, *a.Head(), ts)
+}
+
+func TestAcceptableOnlyFromChainStore(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		store    func(store *sim.ChainStore, b f3.ECChain)
+		value    func(b f3.ECChain) f3.ECChain
+		prepares bool
+	}{
+		{"heaviest", func(store *sim.ChainStore, b f3.ECChain) { store.SetHeaviest(b) },
+			func(b f3.ECChain) f3.ECChain { return b }, true},
+		{"not heaviest", func(store *sim.ChainStore, b f3.ECChain) { store.Add(b) },
+			func(b f3.ECChain) f3.ECChain { return b }, false},
+		{"skipping a tipset", func(store *sim.ChainStore, b f3.ECChain) { store.SetHeaviest(b) },
+			func(b f3.ECChain) f3.ECChain { return f3.ECChain{b[0], b[2]} }, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+			a := sm.Base.Extend(sm.CIDGen.Sample())
+			b := a.Extend(sm.CIDGen.Sample())
+			value := test.value(b)
+			// Only one participant runs, with input a. It learns of b only from its chain store.
+			// The others' CONVERGE tickets must outrank its own, so the participant with the highest rank runs.
+			beacon, err := sm.Beacons.GetBeacon(*sm.Base.Head())
+			require.NoError(t, err)
+			tickets := map[f3.ActorID]f3.Ticket{}
+			var running f3.ActorID
+			for _, p := range sm.Participants {
+				tickets[p.ID()] = f3.NewFakeVRF().MakeTicket(beacon, 0, 1, sim.FakePubKey(p.ID()))
+				if f3.ComputeTicketRank(tickets[p.ID()], 1).Compare(f3.ComputeTicketRank(tickets[running], 1)) > 0 {
+					running = p.ID()
+				}
+			}
+			var others []f3.ActorID
+			for _, p := range sm.Participants {
+				if p.ID() != running {
+					others = append(others, p.ID())
+				}
+			}
+			p := sm.Participants[running]
+			test.store(sm.ChainStores[running], b)
+			p.ReceiveCanonicalChain(a)
+
+			// The others COMMIT bottom in round 0, so the participant moves to round 1.
+			for _, sender := range others {
+				msg := signed(sm, f3.GMessage{Sender: sender, Step: f3.COMMIT})
+				p.ReceiveMessage(&msg)
+			}
+			for i := 0; i < 1000 && p.CurrentRound() < 1 && sm.Network.Tick(sm.Adversary); i++ {
+			}
+			require.Equal(t, 1, p.CurrentRound())
+
+			// The others CONVERGE on a value the participant received only in its chain store.
+			for _, sender := range others {
+				msg := signed(sm, f3.GMessage{Sender: sender, Round: 1, Step: f3.CONVERGE, Value: value,
+					Ticket: tickets[sender]})
+				p.ReceiveMessage(&msg)
+			}
+			var prepared *f3.GMessage
+			for i := 0; i < 1000 && prepared == nil && sm.Network.Tick(sm.Adversary); i++ {
+				prepared = sentMessage(t, sm.Journals[running], 1, f3.PREPARE)
+			}
+
+			// The participant prepares the converged value only if it is in the store's heaviest chain.
+			require.NotNil(t, prepared)
+			if test.prepares {
+				require.Equal(t, value, prepared.Value)
+			} else {
+				require.True(t, prepared.Value.IsZero())
+			}
+		})
+	}
+}
+
+// Returns the message a participant journaled as sent in instance 0 for some round and step, if any.
+func sentMessage(t *testing.T, journal *sim.Journal, round int, step string) *f3.GMessage {
+	entries, err := journal.Entries()
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if entry.Kind == f3.JournalSend && entry.Message.Round == round && entry.Message.Step == step {
+			return entry.Message
+		}
+	}
+	return nil
+}
@@ -12,6 +12,15 @@ const LATENCY_SYNC = 0
 const LATENCY_ASYNC = 0.100
 const MAX_ROUNDS = 10
 const ASYNC_ITERS = 5000
+const LOSSY_ITERS = 100
+const BLS_VRF_ITERS = 20
+const REBROADCAST_DELAY = 0.200
+const REBROADCAST_BACKOFF = 1.5
+const REBROADCAST_MAX_DELAY = 2.0
+const LOSS_RATE = 0.1
+const DELTA_BACKOFF = 1.3
+const DELTA_MIN = 0.100
+const DELTA_MAX = 5.0
 
 // Returns a default Granite configuration.
 func GraniteConfig() f3.GraniteConfig {
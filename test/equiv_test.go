@@ -16,7 +16,7 @@ func TestWitholdCommit1(t *testing.T) {
 		LatencySeed: int64(i),
 		LatencyMean: 0.01, // Near-synchrony
 	}, GraniteConfig(), sim.TraceNone)
-	adv := adversary.NewWitholdCommit(99, sm.Network)
+	adv := adversary.NewWitholdCommit(99, sm.Network, sm.Signer)
 	sm.SetAdversary(adv, 3) // Adversary has 30% of 10 total power.
 
 	a := sm.Base.Extend(sm.CIDGen.Sample())
@@ -30,7 +30,7 @@ func TestWitholdCommit1(t *testing.T) {
 	adv.SetVictim(victims, a)
 
 	adv.Begin()
-	sm.ReceiveChains(sim.ChainCount{4, a}, sim.ChainCount{3, b})
+	sm.ReceiveChains(sim.ChainCount{Count: 4, Chain: a}, sim.ChainCount{Count: 3, Chain: b})
 	ok := sm.Run(MAX_ROUNDS)
 	if !ok {
 		fmt.Printf("%s", sm.Describe())
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/adversary"
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEquivocationDetected(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	adv := adversary.NewEquivocate(99, sm.Network, sm.Signer)
+	sm.SetAdversary(adv, 1)
+
+	evidence := map[f3.ActorID][]*f3.EquivocationEvidence{}
+	for _, p := range sm.Participants {
+		id := p.ID()
+		p.OnEquivocation(func(e *f3.EquivocationEvidence) {
+			evidence[id] = append(evidence[id], e)
+		})
+	}
+
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := sm.Base.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	// The adversary's messages are delivered before the honest participants can decide.
+	adv.Begin(a, b)
+	require.True(t, sm.Run(MAX_ROUNDS), "%s", sm.Describe())
+	require.Equal(t, *a.Head(), *sm.Decision(sm.Participants[0].ID(), 0).Head())
+
+	// Every honest participant reports the adversary once for each of PREPARE and COMMIT.
+	for _, p := range sm.Participants {
+		reported := evidence[p.ID()]
+		require.Len(t, reported, 2)
+		steps := map[string]bool{}
+		for _, e := range reported {
+			require.Equal(t, adv.ID(), e.Sender())
+			require.NoError(t, e.Verify(sm.PowerTable, sm.Signer))
+			steps[e.First.Step] = true
+		}
+		require.Equal(t, map[string]bool{f3.PREPARE: true, f3.COMMIT: true}, steps)
+	}
+}
+
+func TestEquivocationEvidenceVerify(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(1), GraniteConfig(), sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := sm.Base.Extend(sm.CIDGen.Sample())
+	sign := func(step string, value f3.ECChain) f3.GMessage {
+		msg := f3.GMessage{Sender: 0, Instance: 0, Round: 0, Step: step, Value: value}
+		msg.Signature = sm.Signer.Sign(sim.FakePubKey(0), msg.SignaturePayload())
+		return msg
+	}
+
+	valid := f3.EquivocationEvidence{First: sign(f3.PREPARE, a), Second: sign(f3.PREPARE, b)}
+	require.NoError(t, valid.Verify(sm.PowerTable, sm.Signer))
+
+	sameValue := f3.EquivocationEvidence{First: sign(f3.PREPARE, a), Second: sign(f3.PREPARE, a)}
+	require.Error(t, sameValue.Verify(sm.PowerTable, sm.Signer))
+
+	differentSteps := f3.EquivocationEvidence{First: sign(f3.PREPARE, a), Second: sign(f3.COMMIT, b)}
+	require.Error(t, differentSteps.Verify(sm.PowerTable, sm.Signer))
+
+	quality := f3.EquivocationEvidence{First: sign(f3.QUALITY, a), Second: sign(f3.QUALITY, b)}
+	require.Error(t, quality.Verify(sm.PowerTable, sm.Signer))
+
+	forged := valid
+	forged.Second.Signature = []byte("forged")
+	require.Error(t, forged.Verify(sm.PowerTable, sm.Signer))
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+///// Tests of participants crashing and restarting from their journals.
+
+func TestCrashRecovery(t *testing.T) {
+	t.Parallel()
+	// A participant journals one START and up to three SENDs in each instance decided in its first round,
+	// fewer if it catches up to others' decisions.
+	for appends := 1; appends <= 8; appends++ {
+		for i := 0; i < 20; i++ {
+			sm := sim.NewSimulation(newAsyncConfig(4, i), GraniteConfig(), sim.TraceNone)
+			evidence := recordEquivocations(sm)
+			a := sm.Base.Extend(sm.CIDGen.Sample())
+			b := a.Extend(sm.CIDGen.Sample())
+			sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+			sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+
+			// Crash the last participant after journaling some state, and restart it some time later.
+			crashed := len(sm.Participants) - 1
+			id := sm.Participants[crashed].ID()
+			sm.CrashAfter(crashed, appends)
+			for !sm.Network.Crashed(id) && sm.Network.Tick(sm.Adversary) {
+			}
+			if sm.Network.Crashed(id) {
+				for j := 0; j < i && sm.Network.Tick(sm.Adversary); j++ {
+				}
+				restarted := sm.Restart(crashed)
+				restarted.ReceiveCanonicalChain(b)
+			}
+
+			require.True(t, sm.RunInstances(2, MAX_ROUNDS), "appends %d seed %d\n%s", appends, i, sm.Describe())
+			// The restarted participant never sent a message conflicting with one it sent before crashing.
+			require.Empty(t, *evidence, "appends %d seed %d", appends, i)
+		}
+	}
+}
+
+func TestRestartAfterDecision(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	require.True(t, sm.Run(MAX_ROUNDS), "%s", sm.Describe())
+
+	// The journal is compacted to the decision, from which a restarted participant resumes.
+	entries, err := sm.Journals[0].Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, f3.JournalDecide, entries[0].Kind)
+
+	sm.Network.Crash(sm.Participants[0].ID())
+	restarted := sm.Restart(0)
+	finalised, _ := restarted.Finalised()
+	require.Equal(t, *a.Head(), finalised)
+	require.Equal(t, sm.Decided(0), restarted.FinalityCertificate())
+
+	// The restarted participant begins the next instance with a new chain.
+	b := a.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+	require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+	require.Equal(t, *b.Head(), *sm.Decided(1).Head())
+}
+
+func TestJournalFailureHalts(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	diskFull := errors.New("disk full")
+	journal := &failingJournal{Journal: sim.NewJournal(), err: diskFull}
+	halted := sm.Participants[0]
+	require.NoError(t, halted.SetJournal(journal))
+	var failures []error
+	halted.OnFailure(func(err error) {
+		failures = append(failures, err)
+	})
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	for i := 0; i < 1000 && sm.Network.Tick(sm.Adversary); i++ {
+	}
+
+	// The participant which failed to journal its decision halted without recording or sending it,
+	// while the others decided.
+	require.Len(t, failures, 1)
+	require.ErrorIs(t, failures[0], diskFull)
+	require.ErrorIs(t, halted.Failure(), diskFull)
+	require.Nil(t, halted.FinalityCertificate())
+	for _, p := range sm.Participants[1:] {
+		require.NotNil(t, p.FinalityCertificate())
+	}
+	entries, err := journal.Entries()
+	require.NoError(t, err)
+	for _, entry := range entries {
+		require.NotEqual(t, f3.JournalDecide, entry.Kind)
+	}
+	require.Error(t, halted.ReceiveFinalityCertificate(sm.Participants[1].FinalityCertificate()))
+}
+
+// A journal which fails to rewrite, i.e. to record a decision.
+type failingJournal struct {
+	*sim.Journal
+	err error
+}
+
+func (j *failingJournal) Rewrite([]*f3.JournalEntry) error {
+	return j.err
+}
+
+// Records evidence of equivocation reported by any honest participant.
+func recordEquivocations(sm *sim.Simulation) *[]*f3.EquivocationEvidence {
+	var evidence []*f3.EquivocationEvidence
+	for _, p := range sm.Participants {
+		p.OnEquivocation(func(e *f3.EquivocationEvidence) {
+			evidence = append(evidence, e)
+		})
+	}
+	return &evidence
+}
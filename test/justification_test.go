@@ -0,0 +1,84 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJustifiedCommitWithoutPrepares(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		justify func(j *f3.Justification) *f3.Justification
+		decides bool
+	}{
+		{"valid", func(j *f3.Justification) *f3.Justification { return j }, true},
+		{"absent", func(j *f3.Justification) *f3.Justification { return nil }, false},
+		{"forged", func(j *f3.Justification) *f3.Justification {
+			j.Signature = []byte("forged")
+			return j
+		}, false},
+		{"minority", func(j *f3.Justification) *f3.Justification {
+			j.Signers = f3.NewBitfield(1, 2)
+			return j
+		}, false},
+		{"wrong step", func(j *f3.Justification) *f3.Justification {
+			j.Step = f3.COMMIT
+			return j
+		}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+			a := sm.Base.Extend(sm.CIDGen.Sample())
+			p := sm.Participants[0]
+			p.ReceiveCanonicalChain(a)
+
+			// Deliver COMMITs from the other participants directly, without any of their PREPAREs.
+			// Only a valid justification of a strong quorum of PREPARE allows the participant to decide.
+			senders := []f3.ActorID{1, 2, 3}
+			for _, sender := range senders {
+				msg := signed(sm, f3.GMessage{Sender: sender, Step: f3.COMMIT, Value: a})
+				msg.Justification = test.justify(prepareJustification(t, sm, senders, a))
+				p.ReceiveMessage(&msg)
+			}
+
+			if test.decides {
+				require.NotNil(t, p.FinalityCertificate())
+				require.Equal(t, *a.Head(), *p.FinalityCertificate().Head())
+			} else {
+				require.Nil(t, p.FinalityCertificate())
+			}
+		})
+	}
+}
+
+// Signs a message in instance 0, round 0 from its sender.
+func signed(sm *sim.Simulation, msg f3.GMessage) f3.GMessage {
+	msg.Signature = sm.Signer.Sign(sim.FakePubKey(msg.Sender), msg.SignaturePayload())
+	return msg
+}
+
+// Builds a justification from PREPARE messages in instance 0, round 0 from some senders.
+// The senders must be in power table order.
+func prepareJustification(t *testing.T, sm *sim.Simulation, senders []f3.ActorID, value f3.ECChain) *f3.Justification {
+	var indices []int
+	var pubKeys []f3.PubKey
+	var sigs [][]byte
+	for _, sender := range senders {
+		prepare := signed(sm, f3.GMessage{Sender: sender, Step: f3.PREPARE, Value: value})
+		indices = append(indices, sm.PowerTable.Lookup[sender])
+		pubKeys = append(pubKeys, sim.FakePubKey(sender))
+		sigs = append(sigs, prepare.Signature)
+	}
+	agg, err := sm.Signer.Aggregate(pubKeys, sigs)
+	require.NoError(t, err)
+	return &f3.Justification{
+		Round:     0,
+		Step:      f3.PREPARE,
+		Value:     value,
+		Signers:   f3.NewBitfield(indices...),
+		Signature: agg,
+	}
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+///// Tests of consecutive instances, with no adversaries.
+
+func TestSyncMultiInstance(t *testing.T) {
+	sm := sim.NewSimulation(newSyncConfig(4), GraniteConfig(), sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := a.Extend(sm.CIDGen.Sample())
+	// The first chain begins the first instance, and the extended chain is input for the next.
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+
+	require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+	first := sm.Decided(0)
+	second := sm.Decided(1)
+	require.Equal(t, *a.Head(), *first.Head())
+	require.Equal(t, *b.Head(), *second.Head())
+	// The second instance extends the decision of the first.
+	require.Equal(t, *first.Head(), *second.Value.Base())
+}
+
+func TestAsyncMultiInstance(t *testing.T) {
+	t.Parallel()
+	for i := 0; i < ASYNC_ITERS; i++ {
+		sm := sim.NewSimulation(newAsyncConfig(4, i), GraniteConfig(), sim.TraceNone)
+		a := sm.Base.Extend(sm.CIDGen.Sample())
+		b := a.Extend(sm.CIDGen.Sample())
+		c := b.Extend(sm.CIDGen.Sample())
+		sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+		sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: c})
+
+		require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+		// Each instance extends the decision of the previous one.
+		first := sm.Decided(0)
+		second := sm.Decided(1)
+		require.True(t, c.HasTipset(first.Head()))
+		require.Equal(t, *first.Head(), *second.Value.Base())
+		require.True(t, c.HasTipset(second.Head()))
+	}
+}
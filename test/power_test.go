@@ -0,0 +1,54 @@
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowerTableChange(t *testing.T) {
+	for _, test := range []struct {
+		lookback      int
+		expectedTable int
+	}{
+		// Without lookback, the second instance uses the changed table, in which participant 3 has no power.
+		{0, 3},
+		// With lookback, the second instance still uses the genesis table.
+		{1, 4},
+	} {
+		config := GraniteConfig()
+		config.PowerTableLookback = test.lookback
+		sm := sim.NewSimulation(newSyncConfig(4), config, sim.TraceNone)
+		a := sm.Base.Extend(sm.CIDGen.Sample())
+		b := a.Extend(sm.CIDGen.Sample())
+
+		// Remove participant 3's power from the first instance's decision onwards.
+		changed := f3.NewPowerTable()
+		for _, id := range []f3.ActorID{0, 1, 2} {
+			changed.Add(id, big.NewInt(1), sim.FakePubKey(id))
+		}
+		sm.PowerTables.Set(a.Head().Epoch, changed)
+
+		sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+		sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+		require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+
+		first := sm.Decided(0)
+		require.NoError(t, first.Verify(sm.PowerTable, sm.Signer))
+
+		second := sm.Decided(1)
+		require.Equal(t, *b.Head(), *second.Head())
+		table, err := sm.PowerTables.GetPowerTable(*second.Value.Base(), test.lookback)
+		require.NoError(t, err)
+		require.Len(t, table.Entries, test.expectedTable)
+		require.NoError(t, second.Verify(table, sm.Signer))
+		signers, err := second.SignerIDs(table)
+		require.NoError(t, err)
+		if !table.Has(3) {
+			require.NotContains(t, signers, f3.ActorID(3))
+		}
+	}
+}
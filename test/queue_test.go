@@ -0,0 +1,42 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundedQueues(t *testing.T) {
+	config := GraniteConfig()
+	config.MaxFutureInstances = 2
+	config.MaxFutureRounds = 5
+	config.MaxQueuedPerSender = 10
+	config.MaxQueued = 20
+	sm := sim.NewSimulation(newSyncConfig(4), config, sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	b := a.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+
+	// One sender floods a participant with messages for later instances and rounds.
+	p := sm.Participants[0]
+	for n := 0; n < 100; n++ {
+		// Queued for the next instances, up to the sender's limit.
+		p.ReceiveMessage(&f3.GMessage{Sender: 3, Instance: 1 + n%2, Round: 1 + n, Step: f3.PREPARE, Value: b})
+		// Beyond the instance window.
+		p.ReceiveMessage(&f3.GMessage{Sender: 3, Instance: 3 + n, Step: f3.QUALITY, Value: b})
+		// Beyond the round window of the current instance.
+		msg := signed(sm, f3.GMessage{Sender: 3, Round: 6 + n, Step: f3.PREPARE, Value: a})
+		p.ReceiveMessage(&msg)
+		// From a sender not in the power table, so never queued.
+		p.ReceiveMessage(&f3.GMessage{Sender: 99, Instance: 1, Round: n, Step: f3.PREPARE, Value: b})
+	}
+	require.Equal(t, f3.QueueStats{DroppedFuture: 200, DroppedSenderLimit: 90, DroppedUnknownSender: 100},
+		p.QueueStats())
+
+	// The flood does not prevent progress.
+	require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+	require.Equal(t, *b.Head(), *sm.Decided(1).Head())
+}
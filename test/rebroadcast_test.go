@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLossyWithRebroadcast(t *testing.T) {
+	config := GraniteConfig()
+	config.RebroadcastDelay = REBROADCAST_DELAY
+	config.RebroadcastBackoff = REBROADCAST_BACKOFF
+	config.RebroadcastMaxDelay = REBROADCAST_MAX_DELAY
+	for i := 0; i < LOSSY_ITERS; i++ {
+		sm := newLossySimulation(i, config)
+		require.True(t, sm.Run(MAX_ROUNDS), "seed %d: %s", i, sm.Describe())
+	}
+}
+
+func TestLossyWithoutRebroadcast(t *testing.T) {
+	// Without rebroadcast, some instances stall after losing messages.
+	stalled := 0
+	for i := 0; i < LOSSY_ITERS; i++ {
+		sm := newLossySimulation(i, GraniteConfig())
+		if !sm.Run(MAX_ROUNDS) {
+			stalled++
+		}
+	}
+	require.Greater(t, stalled, 0)
+}
+
+func newLossySimulation(seed int, config f3.GraniteConfig) *sim.Simulation {
+	simConfig := newAsyncConfig(4, seed)
+	simConfig.LossRate = LOSS_RATE
+	sm := sim.NewSimulation(simConfig, config, sim.TraceNone)
+	a := sm.Base.Extend(sm.CIDGen.Sample())
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+	return sm
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/f3"
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+///// Tests of consecutive instances under each timeout policy, with no adversaries.
+
+func TestAsyncTimeoutPolicies(t *testing.T) {
+	for _, policy := range []f3.TimeoutKind{f3.TimeoutLinear, f3.TimeoutExponential, f3.TimeoutAdaptive} {
+		policy := policy
+		t.Run(string(policy), func(t *testing.T) {
+			t.Parallel()
+			config := GraniteConfig()
+			config.Timeout = policy
+			config.DeltaBackoff = DELTA_BACKOFF
+			config.DeltaMin = DELTA_MIN
+			config.DeltaMax = DELTA_MAX
+			for i := 0; i < ASYNC_ITERS/10; i++ {
+				sm := sim.NewSimulation(newAsyncConfig(4, i), config, sim.TraceNone)
+				a := sm.Base.Extend(sm.CIDGen.Sample())
+				b := a.Extend(sm.CIDGen.Sample())
+				sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+				sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: b})
+
+				require.True(t, sm.RunInstances(2, MAX_ROUNDS), "%s", sm.Describe())
+				require.True(t, b.HasTipset(sm.Decided(0).Head()))
+				require.True(t, b.HasTipset(sm.Decided(1).Head()))
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxChainLength(t *testing.T) {
+	config := GraniteConfig()
+	config.MaxChainLength = 3
+	sm := sim.NewSimulation(newSyncConfig(4), config, sim.TraceNone)
+	long := sm.Base
+	for j := 0; j < 5; j++ {
+		long = long.Extend(sm.CIDGen.Sample())
+	}
+	sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: long})
+
+	// Each instance's input is truncated to the maximum length, including the base,
+	// so the chain is finalised over successive instances.
+	require.True(t, sm.RunInstances(3, MAX_ROUNDS), "%s", sm.Describe())
+	require.Equal(t, long[2], *sm.Decided(0).Head())
+	require.Equal(t, long[4], *sm.Decided(1).Head())
+	require.Equal(t, long[5], *sm.Decided(2).Head())
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-f3/sim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLossyWithBLSVRF(t *testing.T) {
+	// Tickets are real BLS signatures, so CONVERGE selects by the ranks of tickets a real network would use.
+	config := GraniteConfig()
+	config.RebroadcastDelay = REBROADCAST_DELAY
+	config.RebroadcastBackoff = REBROADCAST_BACKOFF
+	config.RebroadcastMaxDelay = REBROADCAST_MAX_DELAY
+	laterRounds := 0
+	for i := 0; i < BLS_VRF_ITERS; i++ {
+		simConfig := newAsyncConfig(4, i)
+		simConfig.LossRate = LOSS_RATE
+		simConfig.VRF = sim.NewBLSVRF()
+		sm := sim.NewSimulation(simConfig, config, sim.TraceNone)
+		a := sm.Base.Extend(sm.CIDGen.Sample())
+		sm.ReceiveChains(sim.ChainCount{Count: len(sm.Participants), Chain: a})
+
+		require.True(t, sm.Run(MAX_ROUNDS), "seed %d: %s", i, sm.Describe())
+		for _, p := range sm.Participants {
+			cert := sm.Decision(p.ID(), 0)
+			require.Equal(t, *a.Head(), *cert.Head(), "seed %d", i)
+			if cert.Round > 0 {
+				laterRounds++
+			}
+		}
+	}
+	// Some participants decide only after a CONVERGE phase, which ranks the tickets.
+	require.Greater(t, laterRounds, 0)
+}